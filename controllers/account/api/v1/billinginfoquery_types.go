@@ -63,6 +63,14 @@ type PropertyQuery struct {
 	Alias     string  `json:"alias" bson:"alias"`
 	UnitPrice float64 `json:"unit_price" bson:"unit_price"`
 	Unit      string  `json:"unit" bson:"unit"`
+	// Tiers are the graduated prices overriding UnitPrice, by the total used value of the owner in a billing window
+	Tiers []PropertyQueryTier `json:"tiers,omitempty" bson:"tiers,omitempty"`
+}
+
+// PropertyQueryTier prices the used value up to UpTo, the last tier without UpTo is unbounded.
+type PropertyQueryTier struct {
+	UpTo      int64   `json:"up_to,omitempty" bson:"up_to,omitempty"`
+	UnitPrice float64 `json:"unit_price" bson:"unit_price"`
 }
 
 //+kubebuilder:object:root=true
@@ -36,6 +36,14 @@ type BillingRecord struct {
 	ResourceType string `json:"resourceType"`
 	Price        string `json:"price"`
 	DiscountType string `json:"discountType,omitempty"`
+	// Tiers are the graduated prices overriding Price
+	Tiers []BillingRecordTier `json:"tiers,omitempty"`
+}
+
+// BillingRecordTier prices the used value up to UpTo, the last tier without UpTo is unbounded.
+type BillingRecordTier struct {
+	UpTo  int64  `json:"upTo,omitempty"`
+	Price string `json:"price"`
 }
 
 //+kubebuilder:object:root=true
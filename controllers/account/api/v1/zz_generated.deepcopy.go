@@ -245,6 +245,11 @@ func (in *BillingPropertiesForQuery) DeepCopy() *BillingPropertiesForQuery {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BillingRecord) DeepCopyInto(out *BillingRecord) {
 	*out = *in
+	if in.Tiers != nil {
+		in, out := &in.Tiers, &out.Tiers
+		*out = make([]BillingRecordTier, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BillingRecord.
@@ -257,6 +262,21 @@ func (in *BillingRecord) DeepCopy() *BillingRecord {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BillingRecordTier) DeepCopyInto(out *BillingRecordTier) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BillingRecordTier.
+func (in *BillingRecordTier) DeepCopy() *BillingRecordTier {
+	if in == nil {
+		return nil
+	}
+	out := new(BillingRecordTier)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BillingRecordQuery) DeepCopyInto(out *BillingRecordQuery) {
 	*out = *in
@@ -825,7 +845,9 @@ func (in *PriceQueryStatus) DeepCopyInto(out *PriceQueryStatus) {
 	if in.BillingRecords != nil {
 		in, out := &in.BillingRecords, &out.BillingRecords
 		*out = make([]BillingRecord, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -842,6 +864,11 @@ func (in *PriceQueryStatus) DeepCopy() *PriceQueryStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PropertyQuery) DeepCopyInto(out *PropertyQuery) {
 	*out = *in
+	if in.Tiers != nil {
+		in, out := &in.Tiers, &out.Tiers
+		*out = make([]PropertyQueryTier, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PropertyQuery.
@@ -854,6 +881,21 @@ func (in *PropertyQuery) DeepCopy() *PropertyQuery {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PropertyQueryTier) DeepCopyInto(out *PropertyQueryTier) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PropertyQueryTier.
+func (in *PropertyQueryTier) DeepCopy() *PropertyQueryTier {
+	if in == nil {
+		return nil
+	}
+	out := new(PropertyQueryTier)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Transfer) DeepCopyInto(out *Transfer) {
 	*out = *in
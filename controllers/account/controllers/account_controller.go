@@ -80,7 +80,7 @@ type AccountReconciler struct {
 	Scheme                 *runtime.Scheme
 	Logger                 logr.Logger
 	AccountSystemNamespace string
-	DBClient               database.Account
+	DBClient               database.BillingStore
 	CVMDBClient            database.CVM
 	MongoDBURI             string
 	Activities             pkgtypes.Activities
@@ -215,7 +215,7 @@ func (r *AccountReconciler) syncAccount(ctx context.Context, owner string, userN
 func (r *AccountReconciler) syncResourceQuotaAndLimitRange(ctx context.Context, nsName string) error {
 	objs := []client.Object{client.Object(resources.GetDefaultLimitRange(nsName, nsName)), client.Object(resources.GetDefaultResourceQuota(nsName, ResourceQuotaPrefix+nsName))}
 	for i := range objs {
-		err := retry.Retry(10, 1*time.Second, func() error {
+		err := retry.Do(ctx, syncRetryOptions("sync-resource-quota"), func(ctx context.Context) error {
 			_, err := controllerutil.CreateOrUpdate(ctx, r.Client, objs[i], func() error {
 				return nil
 			})
@@ -230,7 +230,7 @@ func (r *AccountReconciler) syncResourceQuotaAndLimitRange(ctx context.Context,
 
 func (r *AccountReconciler) adaptEphemeralStorageLimitRange(ctx context.Context, nsName string) error {
 	limit := resources.GetDefaultLimitRange(nsName, nsName)
-	return retry.Retry(10, 1*time.Second, func() error {
+	return retry.Do(ctx, syncRetryOptions("adapt-ephemeral-storage"), func(ctx context.Context) error {
 		_, err := controllerutil.CreateOrUpdate(ctx, r.Client, limit, func() error {
 			if len(limit.Spec.Limits) == 0 {
 				limit = resources.GetDefaultLimitRange(nsName, nsName)
@@ -245,6 +245,17 @@ func (r *AccountReconciler) adaptEphemeralStorageLimitRange(ctx context.Context,
 	})
 }
 
+// syncRetryOptions retries the transient api server errors of the namespace resource sync,
+// bounded to 15s so that a single reconcile is not blocked for long.
+func syncRetryOptions(name string) retry.Options {
+	return retry.Options{
+		Name:       name,
+		Backoff:    retry.Backoff{Steps: 10, Duration: 200 * time.Millisecond, Factor: 2, Jitter: 0.1, Cap: 5 * time.Second},
+		MaxElapsed: 15 * time.Second,
+		Retryable:  retry.IsRetryableKubeError,
+	}
+}
+
 // DeletePayment delete payments that exist for more than 5 minutes
 func (r *AccountReconciler) DeletePayment(ctx context.Context) error {
 	payments := &accountv1.PaymentList{}
@@ -412,7 +423,7 @@ func getAmountWithDiscount(amount int64, discount pkgtypes.RechargeDiscount) int
 }
 
 func (r *AccountReconciler) BillingCVM() error {
-	cvmMap, err := r.CVMDBClient.GetPendingStateInstance(os.Getenv("LOCAL_REGION"))
+	cvmMap, err := r.CVMDBClient.GetPendingStateInstance(context.Background(), os.Getenv("LOCAL_REGION"))
 	if err != nil {
 		return fmt.Errorf("get pending state instance failed: %v", err)
 	}
@@ -458,12 +469,12 @@ func (r *AccountReconciler) BillingCVM() error {
 			Detail:    "{" + strings.Join(cvmIDsDetail, ",") + "}",
 		}
 		err = r.AccountV2.AddDeductionBalanceWithFunc(&pkgtypes.UserQueryOpts{UID: user.UserUID}, billing.Amount, func() error {
-			if saveErr := r.DBClient.SaveBillings(billing); saveErr != nil {
+			if saveErr := r.DBClient.SaveBillings(context.Background(), billing); saveErr != nil {
 				return fmt.Errorf("save billing failed: %v", saveErr)
 			}
 			return nil
 		}, func() error {
-			if saveErr := r.CVMDBClient.SetDoneStateInstance(cvmIDs...); saveErr != nil {
+			if saveErr := r.CVMDBClient.SetDoneStateInstance(context.Background(), cvmIDs...); saveErr != nil {
 				return fmt.Errorf("set done state instance failed: %v", saveErr)
 			}
 			return nil
@@ -163,7 +163,7 @@ func TestConvertPayment_V1ToV2(t *testing.T) {
 				t.Errorf("failed close connection: %v", err)
 			}
 		}()
-		billings, err := accountV1.GetAllPayment()
+		billings, err := accountV1.GetAllPayment(context.Background())
 		if err != nil {
 			t.Fatalf("failed to get billing: %v", err)
 		}
@@ -99,7 +99,7 @@ func (r *BillingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	// TODO r.处理Unsettle状态的账单
 
-	if exist, lastUpdateTime, _ := r.DBClient.GetBillingLastUpdateTime(owner, v12.Consumption); exist {
+	if exist, lastUpdateTime, _ := r.DBClient.GetBillingLastUpdateTime(ctx, owner, v12.Consumption); exist {
 		if lastUpdateTime.Equal(currentHourTime) || lastUpdateTime.After(currentHourTime) {
 			return ctrl.Result{Requeue: true, RequeueAfter: time.Until(currentHourTime.Add(1*time.Hour + 10*time.Minute))}, nil
 		}
@@ -113,7 +113,7 @@ func (r *BillingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	consumAmount := int64(0)
 	// 计算上次billing到当前的时间之间的整点，左开右闭
 	for t := queryTime.Truncate(time.Hour).Add(time.Hour); t.Before(currentHourTime) || t.Equal(currentHourTime); t = t.Add(time.Hour) {
-		ids, amount, err := r.DBClient.GenerateBillingData(t.Add(-1*time.Hour), t, r.Properties, nsList, getUsername(owner))
+		ids, amount, err := r.DBClient.GenerateBillingData(ctx, t.Add(-1*time.Hour), t, r.Properties, nsList, getUsername(owner))
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("generate billing data failed: %w", err)
 		}
@@ -123,13 +123,18 @@ func (r *BillingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	if consumAmount > 0 {
 		if err := r.rechargeBalance(owner, consumAmount); err != nil {
 			for i := range orderList {
-				if err := r.DBClient.UpdateBillingStatus(orderList[i], resources.Unsettled); err != nil {
+				if err := r.DBClient.UpdateBillingStatus(ctx, orderList[i], resources.Unsettled); err != nil {
 					r.Logger.Error(err, "update billing status failed", "id", orderList[i])
 				}
 			}
 			return ctrl.Result{}, fmt.Errorf("recharge balance failed: %w", err)
 		}
 		r.Logger.V(1).Info("success recharge balance", "owner", owner, "amount", consumAmount)
+		if recommendation, err := r.DBClient.EvaluateSpendingCap(ctx, getUsername(owner), currentHourTime); err != nil {
+			r.Logger.Error(err, "evaluate spending cap failed", "owner", owner)
+		} else if recommendation != nil {
+			r.Logger.Info("owner exceeded spending cap", "owner", owner, "spent", recommendation.Spent, "limit", recommendation.Limit, "suspendAt", recommendation.SuspendAt)
+		}
 	}
 	return ctrl.Result{Requeue: true, RequeueAfter: time.Until(currentHourTime.Add(1*time.Hour + 10*time.Minute))}, nil
 }
@@ -156,16 +161,34 @@ func getOwnNsList(clt client.Client, user string) ([]string, error) {
 	return nsListStr, nil
 }
 
-func (r *BillingReconciler) initDB() error {
-	return r.DBClient.CreateBillingIfNotExist()
+func (r *BillingReconciler) initDB(ctx context.Context) error {
+	if err := r.DBClient.CreateBillingIfNotExist(ctx); err != nil {
+		return err
+	}
+	if err := r.DBClient.CreateInvoiceIfNotExist(ctx); err != nil {
+		return err
+	}
+	if err := r.DBClient.CreateDevboxUsageIfNotExist(ctx); err != nil {
+		return err
+	}
+	return r.DBClient.CreateSpendingCapIfNotExist(ctx)
+}
+
+// ensureIndexes builds the missing indexes in the background, the queries still work without them,
+// only slower, so a failed build is logged and reported by the missing indexes metric instead of failing the startup.
+func (r *BillingReconciler) ensureIndexes(ctx context.Context) {
+	if err := r.DBClient.EnsureIndexes(ctx); err != nil {
+		r.Logger.Error(err, "ensure indexes failed")
+	}
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *BillingReconciler) SetupWithManager(mgr ctrl.Manager, rateOpts controller.Options) error {
 	r.Logger = ctrl.Log.WithName("controller").WithName("Billing")
-	if err := r.initDB(); err != nil {
+	if err := r.initDB(context.Background()); err != nil {
 		r.Logger.Error(err, "init db failed")
 	}
+	go r.ensureIndexes(context.Background())
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Namespace{}, builder.WithPredicates(predicate.Funcs{
 			CreateFunc: func(createEvent event.CreateEvent) bool {
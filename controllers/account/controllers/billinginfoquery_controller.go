@@ -45,7 +45,7 @@ type BillingInfoQueryReconciler struct {
 	client.Client
 	logr.Logger
 	Scheme   *runtime.Scheme
-	DBClient database.Account
+	DBClient database.BillingStore
 	//TODO init
 	AccountV2              database.AccountV2
 	AccountSystemNamespace string
@@ -115,7 +115,7 @@ func (r *BillingInfoQueryReconciler) NamespacesHistoryQuery(ctx context.Context,
 	var nsList []string
 	owner, ok := user.GetAnnotations()[userv1.UserAnnotationOwnerKey]
 	if ok {
-		nsList, err = r.DBClient.GetBillingHistoryNamespaces(nil, nil, int(accountv1.QueryAllType), owner)
+		nsList, err = r.DBClient.GetBillingHistoryNamespaces(ctx, nil, nil, int(accountv1.QueryAllType), owner)
 		if err != nil {
 			return "", fmt.Errorf("get billing history namespaces failed: %w", err)
 		}
@@ -128,8 +128,14 @@ func (r *BillingInfoQueryReconciler) NamespacesHistoryQuery(ctx context.Context,
 	return string(data), nil
 }
 
-func (r *BillingInfoQueryReconciler) PropertiesQuery(_ context.Context, _ ctrl.Request, _ *accountv1.BillingInfoQuery) (result string, err error) {
-	data, err := json.Marshal(r.propertiesQuery)
+func (r *BillingInfoQueryReconciler) PropertiesQuery(_ context.Context, _ ctrl.Request, billingInfoQuery *accountv1.BillingInfoQuery) (result string, err error) {
+	// the prices are reported with the discount of the owner, as they are charged
+	owner := getUsername(billingInfoQuery.Namespace)
+	properties := make([]accountv1.PropertyQuery, len(r.propertiesQuery))
+	for i := range r.propertiesQuery {
+		properties[i] = discountPropertyQuery(r.propertiesQuery[i], r.Properties.StringMap[r.propertiesQuery[i].Name].DiscountRate(owner))
+	}
+	data, err := json.Marshal(properties)
 	if err != nil {
 		return "", fmt.Errorf("marshal properties query failed: %w", err)
 	}
@@ -173,11 +179,27 @@ func (r *BillingInfoQueryReconciler) ConvertPropertiesToQuery() error {
 			Unit:      types.UnitString,
 			Alias:     types.Alias,
 		}
+		for _, tier := range types.Tiers {
+			property.Tiers = append(property.Tiers, accountv1.PropertyQueryTier{UpTo: tier.UpTo, UnitPrice: tier.UnitPrice})
+		}
 		r.propertiesQuery = append(r.propertiesQuery, property)
 	}
 	return nil
 }
 
+func discountPropertyQuery(property accountv1.PropertyQuery, rate float64) accountv1.PropertyQuery {
+	if rate == 1 {
+		return property
+	}
+	property.UnitPrice *= rate
+	tiers := make([]accountv1.PropertyQueryTier, len(property.Tiers))
+	for i, tier := range property.Tiers {
+		tiers[i] = accountv1.PropertyQueryTier{UpTo: tier.UpTo, UnitPrice: tier.UnitPrice * rate}
+	}
+	property.Tiers = tiers
+	return property
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *BillingInfoQueryReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.Logger = ctrl.Log.WithName("controllers").WithName("BillingInfoQuery")
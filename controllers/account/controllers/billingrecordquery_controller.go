@@ -109,7 +109,7 @@ func (r *BillingRecordQueryReconciler) Reconcile(ctx context.Context, req ctrl.R
 		return ctrl.Result{}, err
 	}
 
-	err = dbClient.QueryBillingRecords(billingRecordQuery, getUsername(billingRecordQuery.Namespace))
+	err = dbClient.QueryBillingRecords(ctx, billingRecordQuery, getUsername(billingRecordQuery.Namespace))
 	if err != nil {
 		r.Logger.Error(err, "query billing records failed")
 		return ctrl.Result{Requeue: true}, err
@@ -149,6 +149,7 @@ func (r *BillingRecordQueryReconciler) ReconcilePriceQuery(ctx context.Context,
 		return ctrl.Result{}, err
 	}
 	priceQuery.Status.BillingRecords = make([]accountv1.BillingRecord, 0)
+	owner := getUsername(priceQuery.Namespace)
 	for _, property := range resources.DefaultPropertyTypeLS.Types {
 		displayName, displayPrice := property.Name, property.UnitPrice
 		if resources.IsGpuResource(property.Name) && property.Alias != "" {
@@ -157,10 +158,19 @@ func (r *BillingRecordQueryReconciler) ReconcilePriceQuery(ctx context.Context,
 		if property.ViewPrice > 0 {
 			displayPrice = property.ViewPrice
 		}
-		priceQuery.Status.BillingRecords = append(priceQuery.Status.BillingRecords, accountv1.BillingRecord{
+		// the prices are reported with the tiers and the discount of the owner, as they are charged
+		rate := property.DiscountRate(owner)
+		record := accountv1.BillingRecord{
 			ResourceType: displayName,
-			Price:        strconv.FormatFloat(displayPrice, 'f', -1, 64),
-		})
+			Price:        strconv.FormatFloat(displayPrice*rate, 'f', -1, 64),
+		}
+		for _, tier := range property.Tiers {
+			record.Tiers = append(record.Tiers, accountv1.BillingRecordTier{
+				UpTo:  tier.UpTo,
+				Price: strconv.FormatFloat(tier.UnitPrice*rate, 'f', -1, 64),
+			})
+		}
+		priceQuery.Status.BillingRecords = append(priceQuery.Status.BillingRecords, record)
 	}
 	if err := r.Status().Update(ctx, priceQuery); err != nil {
 		r.Logger.Error(err, "update price query status failed")
@@ -102,7 +102,7 @@ func (r *NamespaceBillingHistoryReconciler) reconcile(ctx context.Context, req c
 	if !ok {
 		return fmt.Errorf("user %s has no annotations %s", user.Name, userv1.UserLabelOwnerKey)
 	}
-	nsList, err := dbClient.GetBillingHistoryNamespaceList(&nsHistory.Spec, owner)
+	nsList, err := dbClient.GetBillingHistoryNamespaceList(ctx, &nsHistory.Spec, owner)
 	if err != nil {
 		return fmt.Errorf("get billing history namespace list failed: %w", err)
 	}
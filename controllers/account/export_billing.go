@@ -0,0 +1,73 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/billingexport"
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/database/mongo"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// exportBillingCommand exports the hourly billing amounts to VictoriaMetrics and exits, it is meant to be run by a CronJob.
+const exportBillingCommand = "export-billing"
+
+func runExportBilling(args []string) int {
+	var (
+		importURL string
+		window    time.Duration
+		timeout   time.Duration
+	)
+	fs := flag.NewFlagSet(exportBillingCommand, flag.ExitOnError)
+	fs.StringVar(&importURL, "vm-import-url", os.Getenv(billingexport.EnvVMImportURL), "The VictoriaMetrics prometheus import url, eg: http://vminsert.monitor-system:8480/insert/0/prometheus/api/v1/import/prometheus")
+	fs.DurationVar(&window, "window", 2*time.Hour, "The window before the current hour to export, overlapping windows are deduplicated by VictoriaMetrics.")
+	fs.DurationVar(&timeout, "timeout", 5*time.Minute, "The timeout of the export.")
+	opts := zap.Options{}
+	opts.BindFlags(fs)
+	_ = fs.Parse(args)
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	log := ctrl.Log.WithName(exportBillingCommand)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	dbClient, err := mongo.NewMongoInterface(ctx, os.Getenv(database.MongoURI))
+	if err != nil {
+		log.Error(err, "unable to connect to mongo")
+		return 1
+	}
+	defer func() {
+		if err := dbClient.Disconnect(context.Background()); err != nil {
+			log.Error(err, "unable to disconnect from mongo")
+		}
+	}()
+
+	end := time.Now().UTC().Truncate(time.Hour)
+	start := end.Add(-window)
+	count, err := billingexport.Export(ctx, dbClient, &http.Client{Timeout: timeout}, importURL, start, end)
+	if err != nil {
+		log.Error(err, "unable to export billing data", "start", start, "end", end)
+		return 1
+	}
+	log.Info("exported billing data", "start", start, "end", end, "samples", count)
+	return 0
+}
@@ -0,0 +1,116 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/database/mongo"
+	"github.com/labring/sealos/controllers/pkg/invoice"
+	"github.com/labring/sealos/controllers/pkg/resources"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// generateInvoiceCommand generates the monthly invoice of an owner, renders it as csv or pdf and exits.
+// Generating an invoice again returns the stored one, it is meant to be run by hand or by a CronJob.
+const generateInvoiceCommand = "generate-invoice"
+
+func runGenerateInvoice(args []string) int {
+	var (
+		owner   string
+		month   string
+		format  string
+		output  string
+		timeout time.Duration
+	)
+	fs := flag.NewFlagSet(generateInvoiceCommand, flag.ExitOnError)
+	fs.StringVar(&owner, "owner", "", "The owner to generate the invoice for.")
+	fs.StringVar(&month, "month", "", "The month of the invoice in the form 2006-01, the previous month if empty.")
+	fs.StringVar(&format, "format", "csv", "The format to render the invoice in, csv or pdf.")
+	fs.StringVar(&output, "output", "", "The file to write the invoice to, stdout if empty.")
+	fs.DurationVar(&timeout, "timeout", 5*time.Minute, "The timeout of the invoice generation.")
+	opts := zap.Options{}
+	opts.BindFlags(fs)
+	_ = fs.Parse(args)
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	log := ctrl.Log.WithName(generateInvoiceCommand)
+
+	if owner == "" {
+		log.Info("owner is required")
+		return 1
+	}
+	var render func(io.Writer, *resources.Invoice) error
+	switch format {
+	case "csv":
+		render = invoice.WriteCSV
+	case "pdf":
+		render = invoice.WritePDF
+	default:
+		log.Error(fmt.Errorf("unsupported format %q", format), "format must be csv or pdf")
+		return 1
+	}
+	period := time.Now().UTC().AddDate(0, -1, 0)
+	if month != "" {
+		t, err := time.Parse("2006-01", month)
+		if err != nil {
+			log.Error(err, "unable to parse month")
+			return 1
+		}
+		period = t
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	dbClient, err := mongo.NewMongoInterface(ctx, os.Getenv(database.MongoURI))
+	if err != nil {
+		log.Error(err, "unable to connect to mongo")
+		return 1
+	}
+	defer func() {
+		if err := dbClient.Disconnect(context.Background()); err != nil {
+			log.Error(err, "unable to disconnect from mongo")
+		}
+	}()
+
+	inv, err := dbClient.GenerateInvoice(ctx, owner, period)
+	if err != nil {
+		log.Error(err, "unable to generate invoice", "owner", owner, "period", period)
+		return 1
+	}
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			log.Error(err, "unable to create invoice file", "output", output)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := render(w, inv); err != nil {
+		log.Error(err, "unable to render invoice", "number", inv.Number)
+		return 1
+	}
+	log.Info("generated invoice", "owner", owner, "number", inv.Number, "amount", inv.Amount)
+	return 0
+}
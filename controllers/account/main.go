@@ -41,6 +41,7 @@ import (
 	"github.com/labring/sealos/controllers/pkg/database"
 
 	notificationv1 "github.com/labring/sealos/controllers/pkg/notification/api/v1"
+	"github.com/labring/sealos/controllers/pkg/utils/kubeclient"
 	rate "github.com/labring/sealos/controllers/pkg/utils/rate"
 	userv1 "github.com/labring/sealos/controllers/user/api/v1"
 
@@ -74,6 +75,16 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case exportBillingCommand:
+			os.Exit(runExportBilling(os.Args[2:]))
+		case reconcileBillingCommand:
+			os.Exit(runReconcileBilling(os.Args[2:]))
+		case generateInvoiceCommand:
+			os.Exit(runGenerateInvoice(os.Args[2:]))
+		}
+	}
 	var (
 		metricsAddr          string
 		enableLeaderElection bool
@@ -81,6 +92,7 @@ func main() {
 		concurrent           int
 		development          bool
 		rateLimiterOptions   rate.LimiterOptions
+		kubeClientOptions    kubeclient.Options
 		leaseDuration        time.Duration
 		renewDeadline        time.Duration
 		retryPeriod          time.Duration
@@ -99,6 +111,7 @@ func main() {
 		Development: development,
 	}
 	rateLimiterOptions.BindFlags(flag.CommandLine)
+	kubeClientOptions.BindFlags(flag.CommandLine, "account-controller")
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
@@ -109,7 +122,7 @@ func main() {
 	//	setupLog.Error(err, "unable to load .env file")
 	//}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgr, err := ctrl.NewManager(kubeClientOptions.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
@@ -237,7 +250,7 @@ func main() {
 		mgr.GetWebhookServer().Register("/validate-v1-sealos-cloud", &webhook.Admission{Handler: &accountv1.DebtValidate{Client: mgr.GetClient(), AccountV2: v2Account}})
 	}
 
-	err = dbClient.InitDefaultPropertyTypeLS()
+	err = dbClient.InitDefaultPropertyTypeLS(context.Background())
 	if err != nil {
 		setupLog.Error(err, "unable to get property type")
 		os.Exit(1)
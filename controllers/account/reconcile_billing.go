@@ -0,0 +1,184 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/database/cockroach"
+	"github.com/labring/sealos/controllers/pkg/database/mongo"
+	"github.com/labring/sealos/controllers/pkg/reconcile"
+	"github.com/labring/sealos/controllers/pkg/resources"
+	userv1 "github.com/labring/sealos/controllers/user/api/v1"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// reconcileBillingCommand compares the stored billing of an owner with the re-aggregated usage, writes the
+// discrepancy report and exits. It only reports unless -correct is set, it is meant to be run by hand or by a CronJob.
+const reconcileBillingCommand = "reconcile-billing"
+
+func runReconcileBilling(args []string) int {
+	var (
+		owner      string
+		namespaces string
+		window     time.Duration
+		endTime    string
+		output     string
+		correct    bool
+		timeout    time.Duration
+	)
+	fs := flag.NewFlagSet(reconcileBillingCommand, flag.ExitOnError)
+	fs.StringVar(&owner, "owner", "", "The owner to reconcile.")
+	fs.StringVar(&namespaces, "namespaces", "", "The comma separated namespaces to reconcile, all namespaces of the owner and the namespaces billed to the owner in the window if empty.")
+	fs.DurationVar(&window, "window", 24*time.Hour, "The window before the end time to reconcile.")
+	fs.StringVar(&endTime, "end", "", "The RFC3339 end time of the window, truncated to the hour, the current hour if empty.")
+	fs.StringVar(&output, "output", "", "The file to write the csv report to, stdout if empty.")
+	fs.BoolVar(&correct, "correct", false, "Issue correction billing for under-billed usage and deduct it from the balance, otherwise only report.")
+	fs.DurationVar(&timeout, "timeout", 10*time.Minute, "The timeout of the reconciliation.")
+	opts := zap.Options{}
+	opts.BindFlags(fs)
+	_ = fs.Parse(args)
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	log := ctrl.Log.WithName(reconcileBillingCommand)
+
+	if owner == "" {
+		log.Info("owner is required")
+		return 1
+	}
+	end := time.Now().UTC()
+	if endTime != "" {
+		t, err := time.Parse(time.RFC3339, endTime)
+		if err != nil {
+			log.Error(err, "unable to parse end time")
+			return 1
+		}
+		end = t
+	}
+	end = end.UTC().Truncate(time.Hour)
+	start := end.Add(-window)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	dbClient, err := mongo.NewMongoInterface(ctx, os.Getenv(database.MongoURI))
+	if err != nil {
+		log.Error(err, "unable to connect to mongo")
+		return 1
+	}
+	defer func() {
+		if err := dbClient.Disconnect(context.Background()); err != nil {
+			log.Error(err, "unable to disconnect from mongo")
+		}
+	}()
+	if err := dbClient.InitDefaultPropertyTypeLS(ctx); err != nil {
+		log.Error(err, "unable to get property type")
+		return 1
+	}
+
+	reconcileOpts := reconcile.Options{Owner: owner, StartTime: start, EndTime: end, Correct: correct}
+	if namespaces != "" {
+		reconcileOpts.Namespaces = strings.Split(namespaces, ",")
+	} else if reconcileOpts.Namespaces, err = getOwnerNamespaces(ctx, dbClient, owner, start, end); err != nil {
+		log.Error(err, "unable to get the namespaces of owner", "owner", owner)
+		return 1
+	}
+	if correct {
+		v2Account, err := cockroach.NewCockRoach(os.Getenv(database.GlobalCockroachURI), os.Getenv(database.LocalCockroachURI))
+		if err != nil {
+			log.Error(err, "unable to connect to cockroach")
+			return 1
+		}
+		defer func() {
+			if err := v2Account.Close(); err != nil {
+				log.Error(err, "unable to disconnect from cockroach")
+			}
+		}()
+		reconcileOpts.Deductor = v2Account
+	}
+
+	report, err := reconcile.Run(ctx, dbClient, resources.DefaultPropertyTypeLS, reconcileOpts)
+	if report != nil {
+		var w io.Writer = os.Stdout
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				log.Error(err, "unable to create report file", "output", output)
+				return 1
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := report.WriteCSV(w); err != nil {
+			log.Error(err, "unable to write report")
+			return 1
+		}
+	}
+	if err != nil {
+		log.Error(err, "unable to reconcile billing", "owner", owner, "start", start, "end", end)
+		return 1
+	}
+	log.Info("reconciled billing", "owner", owner, "start", start, "end", end, "discrepancies", len(report.Discrepancies), "corrections", len(report.Corrections))
+	return 0
+}
+
+// getOwnerNamespaces returns the namespaces labelled with owner, whose usage the billing controller aggregates whether
+// or not it was billed, and the namespaces billed to owner in the window, which covers the namespaces deleted since.
+func getOwnerNamespaces(ctx context.Context, dbClient database.Account, owner string, start, end time.Time) ([]string, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kube config: %w", err)
+	}
+	kubeClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kube client: %w", err)
+	}
+	nsList := &corev1.NamespaceList{}
+	if err := kubeClient.List(ctx, nsList, client.MatchingLabels{userv1.UserLabelOwnerKey: owner}); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	billed, err := dbClient.GetBillingHistoryNamespaces(ctx, &start, &end, int(accountv1.Consumption), owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get billing history namespaces: %w", err)
+	}
+	seen := make(map[string]bool, len(nsList.Items)+len(billed))
+	var namespaces []string
+	for _, ns := range nsList.Items {
+		if !seen[ns.Name] {
+			seen[ns.Name] = true
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+	for _, ns := range billed {
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
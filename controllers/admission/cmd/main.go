@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	v1 "github.com/labring/sealos/controllers/admission/api/v1"
+	"github.com/labring/sealos/controllers/pkg/utils/kubeclient"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -52,6 +53,7 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var kubeClientOptions kubeclient.Options
 	var ingressAnnotationString string
 	var domains v1.DomainList
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
@@ -64,6 +66,7 @@ func main() {
 	opts := zap.Options{
 		Development: true,
 	}
+	kubeClientOptions.BindFlags(flag.CommandLine, "admission-controller")
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
@@ -92,7 +95,7 @@ func main() {
 		}
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgr, err := ctrl.NewManager(kubeClientOptions.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
@@ -33,6 +33,7 @@ import (
 
 	adminerv1 "github.com/labring/sealos/controllers/db/adminer/api/v1"
 	"github.com/labring/sealos/controllers/db/adminer/controllers"
+	"github.com/labring/sealos/controllers/pkg/utils/kubeclient"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -51,6 +52,7 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var kubeClientOptions kubeclient.Options
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -59,12 +61,13 @@ func main() {
 	opts := zap.Options{
 		Development: true,
 	}
+	kubeClientOptions.BindFlags(flag.CommandLine, "adminer-controller")
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgr, err := ctrl.NewManager(kubeClientOptions.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
@@ -15,6 +15,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -53,7 +54,11 @@ func PresetAdminUser() error {
 	if err != nil {
 		return fmt.Errorf("failed to parse region %s uid: %v", os.Getenv(cockroach.EnvLocalRegion), err)
 	}
-	err = retry.Retry(10, 3*time.Second, func() error {
+	// the tables are created by the account service, wait for it on every error
+	err = retry.Do(context.Background(), retry.Options{
+		Name:    "check-user-table",
+		Backoff: retry.Backoff{Steps: 10, Duration: 3 * time.Second, Factor: 2, Jitter: 0.1, Cap: 30 * time.Second},
+	}, func(context.Context) error {
 		tableTypes := []interface{}{
 			types.User{},
 			types.Region{},
@@ -0,0 +1,97 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package billingexport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/database"
+)
+
+// EnvVMImportURL is the VictoriaMetrics prometheus import endpoint, eg: the vminsert of service/database/deploy
+// http://vminsert.monitor-system:8480/insert/0/prometheus/api/v1/import/prometheus
+const EnvVMImportURL = "BILLING_EXPORT_VM_IMPORT_URL"
+
+// MetricName is the exported series, the value is the hourly consumption in yuan.
+const MetricName = "sealos_billing_amount_yuan"
+
+// amount unit: 1000000 = 1¥
+const amountUnit = 1000000
+
+// WritePrometheus writes the samples in the prometheus text format with millisecond timestamps.
+func WritePrometheus(w io.Writer, samples []database.BillingAmountSample) error {
+	var buf bytes.Buffer
+	for _, s := range samples {
+		fmt.Fprintf(&buf, "%s{namespace=\"%s\",app_type=\"%s\"} %s %d\n", MetricName,
+			escapeLabelValue(s.Namespace), escapeLabelValue(s.AppType),
+			strconv.FormatFloat(float64(s.Amount)/amountUnit, 'f', -1, 64), s.Time.UnixMilli())
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write billing samples: %w", err)
+	}
+	return nil
+}
+
+// Push posts the samples to the VictoriaMetrics import endpoint.
+func Push(ctx context.Context, client *http.Client, url string, samples []database.BillingAmountSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	if err := WritePrometheus(&body, samples); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create import request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push billing samples: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to push billing samples: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Export pushes the hourly billing amounts of [startTime, endTime), truncated to the hour, and returns the sample count.
+// Re-exporting a window writes the same timestamps again, which VictoriaMetrics deduplicates.
+func Export(ctx context.Context, db database.BillingStore, client *http.Client, url string, startTime, endTime time.Time) (int, error) {
+	if url == "" {
+		return 0, fmt.Errorf("victoria metrics import url is empty")
+	}
+	samples, err := db.GetBillingAmountSeries(ctx, startTime.UTC().Truncate(time.Hour), endTime.UTC().Truncate(time.Hour))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get billing amount series: %w", err)
+	}
+	return len(samples), Push(ctx, client, url, samples)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
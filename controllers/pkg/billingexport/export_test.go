@@ -0,0 +1,71 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package billingexport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/database/fake"
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+// store returns the samples instead of aggregating the billing, the aggregation is tested against mongo.
+type store struct {
+	*fake.Database
+	samples []database.BillingAmountSample
+}
+
+func (s *store) GetBillingAmountSeries(_ context.Context, startTime, endTime time.Time) ([]database.BillingAmountSample, error) {
+	var samples []database.BillingAmountSample
+	for _, sample := range s.samples {
+		if !sample.Time.Before(startTime) && sample.Time.Before(endTime) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples, nil
+}
+
+func TestExport(t *testing.T) {
+	hour := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	db := &store{Database: fake.NewDatabase(), samples: []database.BillingAmountSample{
+		{Namespace: `ns-"b"`, AppType: resources.CVM, Time: hour, Amount: 25},
+		{Namespace: "ns-a", AppType: resources.APP, Time: hour, Amount: 2000000},
+		{Namespace: "ns-a", AppType: resources.APP, Time: hour.Add(time.Hour), Amount: 1},
+	}}
+
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = string(body)
+	}))
+	defer server.Close()
+
+	count, err := Export(context.Background(), db, server.Client(), server.URL, hour, hour.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	want := `sealos_billing_amount_yuan{namespace="ns-\"b\"",app_type="CLOUD-VM"} 0.000025 1704070800000
+sealos_billing_amount_yuan{namespace="ns-a",app_type="APP"} 2 1704070800000
+`
+	if count != 2 || got != want {
+		t.Errorf("Export() = %d samples, pushed %q, want %q", count, got, want)
+	}
+}
@@ -0,0 +1,804 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fake provides an in-memory implementation of database.Interface,
+// so that consumers of the account, traffic and cvm databases can be tested without a live MongoDB.
+// It only stores and filters records, the methods that aggregate or price them (billing generation,
+// reconciliation, invoices, cost breakdowns, ...) return ErrNotSupported and are tested against mongo.
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/common"
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/resources"
+	"github.com/labring/sealos/controllers/pkg/types"
+)
+
+var _ database.Interface = &Database{}
+
+// ErrNotSupported is returned by the methods that aggregate or price the stored records.
+var ErrNotSupported = errors.New("not supported by the fake database")
+
+// TrafficRecord is a traffic document of the network manager.
+type TrafficRecord struct {
+	Namespace   string
+	PodName     string
+	PodType     uint8
+	PodTypeName string
+	Timestamp   time.Time
+	SentBytes   int64
+	RecvBytes   int64
+}
+
+type Database struct {
+	mu             sync.RWMutex
+	billings       []resources.Billing
+	monitors       []resources.Monitor
+	prices         map[string]resources.Price
+	properties     []resources.PropertyType
+	meteringTimes  map[string]time.Time
+	traffic        []TrafficRecord
+	trafficArchive []TrafficRecord
+	trafficTTL     time.Duration
+	nodePorts      []resources.NodePortTraffic
+	cvm            []types.CVMBilling
+	invoices       []resources.Invoice
+	devboxUsage    []resources.DevboxUsage
+	spendingCaps   map[string]resources.SpendingCap
+	suspends       []resources.SuspendRecommendation
+	watchers       map[*watcher]struct{}
+}
+
+// watcher queues the events of a WatchBillings call, so that writers never wait for a slow reader.
+type watcher struct {
+	owner  string
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	queue  []database.BillingEvent
+	wake   chan struct{}
+}
+
+func NewDatabase() *Database {
+	return &Database{
+		prices:        make(map[string]resources.Price),
+		meteringTimes: make(map[string]time.Time),
+		spendingCaps:  make(map[string]resources.SpendingCap),
+		watchers:      make(map[*watcher]struct{}),
+	}
+}
+
+// SetPrices seeds the prices returned by GetAllPricesMap.
+func (d *Database) SetPrices(prices ...resources.Price) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range prices {
+		d.prices[prices[i].Property] = prices[i]
+	}
+}
+
+// SetMeteringTime seeds the last metering time of category and property.
+func (d *Database) SetMeteringTime(category, property string, t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.meteringTimes[category+"/"+property] = t
+}
+
+// AddTraffic seeds traffic records.
+func (d *Database) AddTraffic(records ...TrafficRecord) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.traffic = append(d.traffic, records...)
+}
+
+// AddCVMBilling seeds cvm billing instances.
+func (d *Database) AddCVMBilling(billings ...types.CVMBilling) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cvm = append(d.cvm, billings...)
+}
+
+// AddInvoices seeds invoices returned by GetInvoice.
+func (d *Database) AddInvoices(invoices ...resources.Invoice) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.invoices = append(d.invoices, invoices...)
+}
+
+// AddSuspendRecommendations seeds recommendations returned by GetDueSuspendRecommendations.
+func (d *Database) AddSuspendRecommendations(recommendations ...resources.SuspendRecommendation) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.suspends = append(d.suspends, recommendations...)
+}
+
+// Billings returns a copy of all saved billings.
+func (d *Database) Billings() []resources.Billing {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return append([]resources.Billing(nil), d.billings...)
+}
+
+// Monitors returns a copy of all inserted monitors.
+func (d *Database) Monitors() []resources.Monitor {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return append([]resources.Monitor(nil), d.monitors...)
+}
+
+func (d *Database) Disconnect(_ context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for w := range d.watchers {
+		w.cancel()
+		delete(d.watchers, w)
+	}
+	return nil
+}
+
+func (d *Database) CreateBillingIfNotExist(_ context.Context) error {
+	return nil
+}
+
+func (d *Database) CreateMonitorTimeSeriesIfNotExist(_ context.Context, _ time.Time) error {
+	return nil
+}
+
+func (d *Database) CreateInvoiceIfNotExist(_ context.Context) error {
+	return nil
+}
+
+func (d *Database) CreateDevboxUsageIfNotExist(_ context.Context) error {
+	return nil
+}
+
+func (d *Database) CreateSpendingCapIfNotExist(_ context.Context) error {
+	return nil
+}
+
+func (d *Database) EnsureIndexes(_ context.Context) error {
+	return nil
+}
+
+func (d *Database) GetBillingLastUpdateTime(_ context.Context, owner string, _type common.Type) (bool, time.Time, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var (
+		found  bool
+		latest time.Time
+	)
+	for i := range d.billings {
+		b := d.billings[i]
+		// skip cvm billing time
+		if b.Owner != owner || b.Type != _type || b.AppType == resources.AppType[resources.CVM] {
+			continue
+		}
+		if !found || b.Time.After(latest) {
+			found, latest = true, b.Time
+		}
+	}
+	return found, latest.UTC(), nil
+}
+
+func (d *Database) GetBillingHistoryNamespaceList(ctx context.Context, ns *accountv1.NamespaceBillingHistorySpec, owner string) ([]string, error) {
+	var startTime, endTime *time.Time
+	if ns.StartTime != ns.EndTime {
+		startTime, endTime = &ns.StartTime.Time, &ns.EndTime.Time
+	}
+	return d.GetBillingHistoryNamespaces(ctx, startTime, endTime, int(ns.Type), owner)
+}
+
+func (d *Database) GetBillingHistoryNamespaces(_ context.Context, startTime, endTime *time.Time, billType int, owner string) ([]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	set := make(map[string]struct{})
+	namespaces := []string{}
+	for i := range d.billings {
+		b := d.billings[i]
+		if b.Owner != owner {
+			continue
+		}
+		if startTime != nil && endTime != nil && (b.Time.Before(*startTime) || b.Time.After(*endTime)) {
+			continue
+		}
+		if billType != -1 && int(b.Type) != billType {
+			continue
+		}
+		if _, ok := set[b.Namespace]; !ok {
+			set[b.Namespace] = struct{}{}
+			namespaces = append(namespaces, b.Namespace)
+		}
+	}
+	return namespaces, nil
+}
+
+func (d *Database) SaveBillings(_ context.Context, billing ...*resources.Billing) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, b := range billing {
+		for i := range d.billings {
+			if d.billings[i].Owner == b.Owner && d.billings[i].OrderID == b.OrderID {
+				return fmt.Errorf("duplicate billing owner %s order id %s", b.Owner, b.OrderID)
+			}
+		}
+		d.billings = append(d.billings, *b)
+		d.notify(database.BillingEventInsert, *b)
+	}
+	return nil
+}
+
+func (d *Database) QueryBillingRecords(_ context.Context, billingRecordQuery *accountv1.BillingRecordQuery, owner string) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	spec := billingRecordQuery.Spec
+	if spec.OrderID != "" {
+		var items []accountv1.BillingRecordQueryItem
+		for i := range d.billings {
+			b := d.billings[i]
+			if b.OrderID != spec.OrderID || b.Owner != owner {
+				continue
+			}
+			if b.Type != accountv1.Consumption {
+				items = append(items, toQueryItem(b))
+				continue
+			}
+			for _, cost := range b.AppCosts {
+				item := toQueryItem(b)
+				item.Name, item.Amount, item.Costs = cost.Name, cost.Amount, resources.ConvertEnumUsedToString(cost.UsedAmount)
+				items = append(items, item)
+			}
+		}
+		billingRecordQuery.Status.Items, billingRecordQuery.Status.PageLength, billingRecordQuery.Status.TotalCount = items, 1, len(items)
+		return nil
+	}
+	if owner == "" {
+		return fmt.Errorf("owner is empty")
+	}
+
+	var matched []resources.Billing
+	var rechargeAmount, deductionAmount int64
+	for i := range d.billings {
+		b := d.billings[i]
+		if b.Owner != owner || b.Time.Before(spec.StartTime.Time) || b.Time.After(spec.EndTime.Time) {
+			continue
+		}
+		if b.Type == accountv1.Recharge {
+			rechargeAmount += b.Amount
+		}
+		if spec.Type != -1 && b.Type != common.Type(spec.Type) {
+			continue
+		}
+		if spec.Namespace != "" && b.Namespace != spec.Namespace {
+			continue
+		}
+		if spec.AppType != "" && b.AppType != resources.AppType[strings.ToUpper(spec.AppType)] {
+			continue
+		}
+		deductionAmount += b.Amount
+		matched = append(matched, b)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Time.After(matched[j].Time)
+	})
+
+	if spec.PageSize < 1 {
+		return fmt.Errorf("page size must be positive")
+	}
+	// the first page is returned for a page below 1, like the mongo implementation
+	page := spec.Page
+	if page < 1 {
+		page = 1
+	}
+	totalCount := len(matched)
+	start := (page - 1) * spec.PageSize
+	end := start + spec.PageSize
+	if start > totalCount {
+		start = totalCount
+	}
+	if end > totalCount {
+		end = totalCount
+	}
+	var items []accountv1.BillingRecordQueryItem
+	for _, b := range matched[start:end] {
+		item := toQueryItem(b)
+		item.AppType = resources.AppTypeReverse[b.AppType]
+		if len(b.AppCosts) != 0 {
+			costs := make(map[string]int64)
+			for i := range b.AppCosts {
+				for j := range b.AppCosts[i].UsedAmount {
+					costs[resources.DefaultPropertyTypeLS.EnumMap[j].Name] += b.AppCosts[i].UsedAmount[j]
+				}
+			}
+			item.Costs = costs
+		}
+		items = append(items, item)
+	}
+	totalPages := 1
+	if totalCount != 0 {
+		totalPages = (totalCount + spec.PageSize - 1) / spec.PageSize
+	}
+	billingRecordQuery.Status.Items, billingRecordQuery.Status.PageLength, billingRecordQuery.Status.TotalCount,
+		billingRecordQuery.Status.RechargeAmount, billingRecordQuery.Status.DeductionAmount = items, totalPages, totalCount, rechargeAmount, deductionAmount
+	return nil
+}
+
+func toQueryItem(b resources.Billing) accountv1.BillingRecordQueryItem {
+	item := accountv1.BillingRecordQueryItem{
+		Time: metav1.NewTime(b.Time),
+		BillingRecordQueryItemInline: accountv1.BillingRecordQueryItemInline{
+			OrderID:   b.OrderID,
+			Type:      b.Type,
+			Amount:    b.Amount,
+			Namespace: b.Namespace,
+		},
+	}
+	if b.Type == accountv1.Consumption {
+		item.AppType = resources.AppTypeReverse[b.AppType]
+	}
+	if b.Type == accountv1.Recharge {
+		paymentAmount := b.Amount
+		if b.Payment != nil {
+			paymentAmount = b.Payment.Amount
+		}
+		item.Payment = &accountv1.PaymentForQuery{Amount: paymentAmount}
+	}
+	return item
+}
+
+func (d *Database) GetUnsettingBillingHandler(_ context.Context, owner string) ([]resources.BillingHandler, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var results []resources.BillingHandler
+	for i := range d.billings {
+		b := d.billings[i]
+		if b.Owner == owner && b.Status == resources.Unsettled {
+			results = append(results, resources.BillingHandler{OrderID: b.OrderID, Time: b.Time, Amount: b.Amount, Status: b.Status})
+		}
+	}
+	return results, nil
+}
+
+func (d *Database) UpdateBillingStatus(_ context.Context, orderID string, status resources.BillingStatus) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.billings {
+		if d.billings[i].OrderID == orderID {
+			d.billings[i].Status = status
+			d.notify(database.BillingEventUpdate, d.billings[i])
+			return nil
+		}
+	}
+	return nil
+}
+
+func (d *Database) GetAllPayment(_ context.Context) ([]resources.Billing, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var payments []resources.Billing
+	for i := range d.billings {
+		b := d.billings[i]
+		if b.Type == accountv1.Recharge && b.Payment != nil && b.Payment.Amount > 0 {
+			payments = append(payments, b)
+		}
+	}
+	return payments, nil
+}
+
+func (d *Database) GetBillingCount(_ context.Context, accountType common.Type, startTime, endTime time.Time) (count, amount int64, err error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for i := range d.billings {
+		b := d.billings[i]
+		if b.Type == accountType && !b.Time.Before(startTime) && !b.Time.After(endTime) {
+			count++
+			amount += b.Amount
+		}
+	}
+	return count, amount, nil
+}
+
+func (d *Database) GenerateBillingData(_ context.Context, startTime, endTime time.Time, prols *resources.PropertyTypeLS, namespaces []string, owner string) (orderID []string, amount int64, err error) {
+	return nil, 0, ErrNotSupported
+}
+
+func (d *Database) SaveDevboxUsage(_ context.Context, usages ...*resources.DevboxUsage) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range usages {
+		d.devboxUsage = append(d.devboxUsage, *usages[i])
+	}
+	return nil
+}
+
+func (d *Database) AggregateDevboxUsage(_ context.Context, owner string, startTime, endTime time.Time) ([]resources.DevboxUsageSummary, error) {
+	return nil, ErrNotSupported
+}
+
+func (d *Database) SetSpendingCap(_ context.Context, spendingCap *resources.SpendingCap) error {
+	if spendingCap.Owner == "" {
+		return fmt.Errorf("owner is empty")
+	}
+	if spendingCap.MonthlyLimit <= 0 {
+		return fmt.Errorf("monthly limit must be positive")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	spendingCap.UpdatedAt = time.Now().UTC()
+	d.spendingCaps[spendingCap.Owner] = *spendingCap
+	d.deleteSuspendRecommendations(func(r *resources.SuspendRecommendation) bool {
+		return r.Owner == spendingCap.Owner
+	})
+	return nil
+}
+
+func (d *Database) GetSpendingCap(_ context.Context, owner string) (*resources.SpendingCap, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	spendingCap, ok := d.spendingCaps[owner]
+	if !ok {
+		return nil, nil
+	}
+	return &spendingCap, nil
+}
+
+func (d *Database) DeleteSpendingCap(_ context.Context, owner string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.spendingCaps, owner)
+	d.deleteSuspendRecommendations(func(r *resources.SuspendRecommendation) bool {
+		return r.Owner == owner
+	})
+	return nil
+}
+
+// deleteSuspendRecommendations removes the matching recommendations, the caller holds the lock.
+func (d *Database) deleteSuspendRecommendations(match func(*resources.SuspendRecommendation) bool) {
+	suspends := d.suspends[:0]
+	for i := range d.suspends {
+		if !match(&d.suspends[i]) {
+			suspends = append(suspends, d.suspends[i])
+		}
+	}
+	d.suspends = suspends
+}
+
+func (d *Database) EvaluateSpendingCap(_ context.Context, owner string, at time.Time) (*resources.SuspendRecommendation, error) {
+	return nil, ErrNotSupported
+}
+
+func (d *Database) GetDueSuspendRecommendations(_ context.Context, now time.Time) ([]resources.SuspendRecommendation, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	start, _ := resources.InvoicePeriod(now)
+	var recommendations []resources.SuspendRecommendation
+	for i := range d.suspends {
+		if d.suspends[i].PeriodStart.Equal(start) && !d.suspends[i].SuspendAt.After(now) {
+			recommendations = append(recommendations, d.suspends[i])
+		}
+	}
+	return recommendations, nil
+}
+
+func (d *Database) GetBillingAmountSeries(_ context.Context, startTime, endTime time.Time) ([]database.BillingAmountSample, error) {
+	return nil, ErrNotSupported
+}
+
+func (d *Database) GetBillingUsage(_ context.Context, startTime, endTime time.Time, prols *resources.PropertyTypeLS, namespaces []string, owner string) (map[database.BillingUsageKey]int64, map[database.BillingUsageKey]database.BillingUsage, error) {
+	return nil, nil, ErrNotSupported
+}
+
+func (d *Database) GetCostBreakdown(_ context.Context, owner, namespace string, startTime, endTime time.Time, prols *resources.PropertyTypeLS, groupBy database.CostGroupBy) ([]database.CostBreakdownItem, error) {
+	return nil, ErrNotSupported
+}
+
+// WatchBillings delivers the billings saved or updated after the call, waiting for the ack of every event
+// like the mongo implementation. The fake does not persist resume tokens.
+func (d *Database) WatchBillings(ctx context.Context, owner string) (<-chan database.BillingEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &watcher{owner: owner, cancel: cancel, wake: make(chan struct{}, 1)}
+	d.mu.Lock()
+	d.watchers[w] = struct{}{}
+	d.mu.Unlock()
+	events := make(chan database.BillingEvent)
+	ack := make(chan struct{}, 1)
+	go func() {
+		defer close(events)
+		defer func() {
+			d.mu.Lock()
+			delete(d.watchers, w)
+			d.mu.Unlock()
+		}()
+		for {
+			w.mu.Lock()
+			queue := w.queue
+			w.queue = nil
+			w.mu.Unlock()
+			for i := range queue {
+				select {
+				case events <- database.NewBillingEvent(queue[i].Type, queue[i].Billing, ack):
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case <-ack:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-w.wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// notify queues the event for the matching watchers without blocking, it must be called with d.mu held.
+func (d *Database) notify(tp database.BillingEventType, billing resources.Billing) {
+	for w := range d.watchers {
+		if w.owner != "" && w.owner != billing.Owner {
+			continue
+		}
+		w.mu.Lock()
+		w.queue = append(w.queue, database.BillingEvent{Type: tp, Billing: billing})
+		w.mu.Unlock()
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (d *Database) GenerateInvoice(_ context.Context, owner string, period time.Time) (*resources.Invoice, error) {
+	return nil, ErrNotSupported
+}
+
+func (d *Database) GetInvoice(_ context.Context, owner string, period time.Time) (*resources.Invoice, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	start, _ := resources.InvoicePeriod(period)
+	for i := range d.invoices {
+		if d.invoices[i].Owner == owner && d.invoices[i].PeriodStart.Equal(start) {
+			invoice := d.invoices[i]
+			return &invoice, nil
+		}
+	}
+	return nil, nil
+}
+
+func (d *Database) GetAllPricesMap(_ context.Context) (map[string]resources.Price, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	prices := make(map[string]resources.Price, len(d.prices))
+	for k, v := range d.prices {
+		prices[k] = v
+	}
+	return prices, nil
+}
+
+func (d *Database) InitDefaultPropertyTypeLS(_ context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.properties) != 0 {
+		missing, err := resources.MissingDevboxPropertyTypes(d.properties)
+		if err != nil {
+			return err
+		}
+		d.properties = append(d.properties, missing...)
+		resources.DefaultPropertyTypeLS = resources.NewPropertyTypeLS(append([]resources.PropertyType(nil), d.properties...))
+	}
+	return nil
+}
+
+func (d *Database) SavePropertyTypes(_ context.Context, types []resources.PropertyType) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.properties = append(d.properties, types...)
+	return nil
+}
+
+func (d *Database) InsertMonitor(_ context.Context, monitors ...*resources.Monitor) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, mon := range monitors {
+		d.monitors = append(d.monitors, *mon)
+	}
+	return nil
+}
+
+func (d *Database) GetDistinctMonitorCombinations(_ context.Context, startTime, endTime time.Time) ([]resources.Monitor, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	type combination struct {
+		category string
+		name     string
+		tp       uint8
+	}
+	set := make(map[combination]struct{})
+	var monitors []resources.Monitor
+	for i := range d.monitors {
+		mon := d.monitors[i]
+		if mon.Time.Before(startTime) || !mon.Time.Before(endTime) {
+			continue
+		}
+		key := combination{category: mon.Category, name: mon.Name, tp: mon.Type}
+		if _, ok := set[key]; !ok {
+			set[key] = struct{}{}
+			monitors = append(monitors, resources.Monitor{Category: mon.Category, Name: mon.Name, Type: mon.Type})
+		}
+	}
+	return monitors, nil
+}
+
+func (d *Database) DropMonitorCollectionsOlderThan(_ context.Context, days int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// monitor collections are daily, everything before the cutoff day is dropped
+	cutoff := time.Now().UTC().AddDate(0, 0, -days).Truncate(24 * time.Hour)
+	monitors := d.monitors[:0]
+	for i := range d.monitors {
+		if !d.monitors[i].Time.Before(cutoff) {
+			monitors = append(monitors, d.monitors[i])
+		}
+	}
+	d.monitors = monitors
+	return nil
+}
+
+func (d *Database) GetUpdateTimeForCategoryAndPropertyFromMetering(_ context.Context, category string, property string) (time.Time, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.meteringTimes[category+"/"+property], nil
+}
+
+func (d *Database) GetTrafficSentBytes(_ context.Context, startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error) {
+	return d.sumTraffic(true, func(r TrafficRecord) bool {
+		return r.Namespace == namespace && r.PodType == _type && r.PodTypeName == name && !r.Timestamp.Before(startTime) && !r.Timestamp.After(endTime)
+	}), nil
+}
+
+func (d *Database) GetTrafficRecvBytes(_ context.Context, startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error) {
+	return d.sumTraffic(false, func(r TrafficRecord) bool {
+		return r.Namespace == namespace && r.PodType == _type && r.PodTypeName == name && !r.Timestamp.Before(startTime) && !r.Timestamp.After(endTime)
+	}), nil
+}
+
+func (d *Database) GetPodTrafficSentBytes(_ context.Context, startTime, endTime time.Time, namespace string, name string) (int64, error) {
+	return d.sumTraffic(true, func(r TrafficRecord) bool {
+		return r.Namespace == namespace && r.PodName == name && !r.Timestamp.Before(startTime) && r.Timestamp.Before(endTime)
+	}), nil
+}
+
+func (d *Database) GetPodTrafficRecvBytes(_ context.Context, startTime, endTime time.Time, namespace string, name string) (int64, error) {
+	return d.sumTraffic(false, func(r TrafficRecord) bool {
+		return r.Namespace == namespace && r.PodName == name && !r.Timestamp.Before(startTime) && r.Timestamp.Before(endTime)
+	}), nil
+}
+
+func (d *Database) sumTraffic(sent bool, match func(r TrafficRecord) bool) int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	total := int64(0)
+	for i := range d.traffic {
+		if !match(d.traffic[i]) {
+			continue
+		}
+		if sent {
+			total += d.traffic[i].SentBytes
+		} else {
+			total += d.traffic[i].RecvBytes
+		}
+	}
+	return total
+}
+
+func (d *Database) GetTrafficTTL(_ context.Context) (time.Duration, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.trafficTTL, nil
+}
+
+func (d *Database) SetTrafficTTL(_ context.Context, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.trafficTTL = ttl
+	return nil
+}
+
+func (d *Database) CreateTrafficIndexes(_ context.Context) error {
+	return nil
+}
+
+func (d *Database) ArchiveTrafficBefore(_ context.Context, before time.Time) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	archived := make(map[TrafficRecord]struct{}, len(d.trafficArchive))
+	for i := range d.trafficArchive {
+		archived[d.trafficArchive[i]] = struct{}{}
+	}
+	var count int64
+	for i := range d.traffic {
+		if !d.traffic[i].Timestamp.Before(before) {
+			continue
+		}
+		if _, ok := archived[d.traffic[i]]; !ok {
+			d.trafficArchive = append(d.trafficArchive, d.traffic[i])
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (d *Database) InsertNodePortTraffic(_ context.Context, records ...*resources.NodePortTraffic) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range records {
+		d.nodePorts = append(d.nodePorts, *records[i])
+	}
+	return nil
+}
+
+func (d *Database) GetNodePortTraffic(_ context.Context, namespace string, startTime, endTime time.Time) ([]resources.NodePortTrafficSummary, error) {
+	return nil, ErrNotSupported
+}
+
+func (d *Database) CreateNodePortTrafficIfNotExist(_ context.Context) error {
+	return nil
+}
+
+func (d *Database) GetPendingStateInstance(_ context.Context, regionUID string) (cvmMap map[string][]types.CVMBilling, err error) {
+	if regionUID == "" {
+		return nil, fmt.Errorf("region UID is empty")
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	cvmMap = make(map[string][]types.CVMBilling)
+	for i := range d.cvm {
+		if d.cvm[i].State == types.CVMBillingStatePending && d.cvm[i].SealosRegionUID == regionUID {
+			cvmMap[d.cvm[i].SealosUserUID] = append(cvmMap[d.cvm[i].SealosUserUID], d.cvm[i])
+		}
+	}
+	return cvmMap, nil
+}
+
+func (d *Database) SetDoneStateInstance(_ context.Context, instanceIDs ...primitive.ObjectID) error {
+	if len(instanceIDs) == 0 {
+		return fmt.Errorf("instanceIDs is empty")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ids := make(map[primitive.ObjectID]struct{}, len(instanceIDs))
+	for _, id := range instanceIDs {
+		ids[id] = struct{}{}
+	}
+	for i := range d.cvm {
+		if _, ok := ids[d.cvm[i].ID]; ok {
+			d.cvm[i].State = types.CVMBillingStateDone
+		}
+	}
+	return nil
+}
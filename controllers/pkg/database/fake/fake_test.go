@@ -0,0 +1,254 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+func TestDatabase_WatchBillings(t *testing.T) {
+	db := NewDatabase()
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := db.WatchBillings(ctx, "owner1")
+	if err != nil {
+		t.Fatalf("failed to watch billings: %v", err)
+	}
+	if err := db.SaveBillings(ctx,
+		&resources.Billing{OrderID: "order1", Owner: "owner2"},
+		&resources.Billing{OrderID: "order2", Owner: "owner1"},
+	); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+	if err := db.UpdateBillingStatus(ctx, "order2", resources.Settled); err != nil {
+		t.Fatalf("failed to update billing status: %v", err)
+	}
+	want := []database.BillingEvent{
+		{Type: database.BillingEventInsert, Billing: resources.Billing{OrderID: "order2", Owner: "owner1"}},
+		{Type: database.BillingEventUpdate, Billing: resources.Billing{OrderID: "order2", Owner: "owner1", Status: resources.Settled}},
+	}
+	for i := range want {
+		got := <-events
+		got.Ack()
+		if got.Type != want[i].Type || got.Billing.OrderID != want[i].Billing.OrderID || got.Billing.Status != want[i].Billing.Status {
+			t.Errorf("event %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+	cancel()
+	if _, ok := <-events; ok {
+		t.Errorf("events channel is not closed after cancel")
+	}
+}
+
+func TestDatabase_WatchBillings_SlowReader(t *testing.T) {
+	db := NewDatabase()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := db.WatchBillings(ctx, "")
+	if err != nil {
+		t.Fatalf("failed to watch billings: %v", err)
+	}
+	// writers must not wait for the reader
+	const count = 2000
+	done := make(chan error)
+	go func() {
+		for i := 0; i < count; i++ {
+			if err := db.SaveBillings(ctx, &resources.Billing{OrderID: fmt.Sprintf("order%d", i), Owner: "owner1"}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("failed to save billings: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("SaveBillings() blocked on the watcher")
+	}
+	for i := 0; i < count; i++ {
+		got := <-events
+		if got.Billing.OrderID != fmt.Sprintf("order%d", i) {
+			t.Fatalf("event %d = %+v", i, got)
+		}
+		got.Ack()
+	}
+}
+
+func TestDatabase_WatchBillings_Ack(t *testing.T) {
+	db := NewDatabase()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := db.WatchBillings(ctx, "")
+	if err != nil {
+		t.Fatalf("failed to watch billings: %v", err)
+	}
+	if err := db.SaveBillings(ctx, &resources.Billing{OrderID: "order1"}, &resources.Billing{OrderID: "order2"}); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+	first := <-events
+	select {
+	case got := <-events:
+		t.Fatalf("event %+v sent before the ack of %s", got, first.Billing.OrderID)
+	case <-time.After(50 * time.Millisecond):
+	}
+	first.Ack()
+	if got := <-events; got.Billing.OrderID != "order2" {
+		t.Errorf("event after ack = %+v, want order2", got)
+	}
+}
+
+func TestDatabase_QueryBillingRecords(t *testing.T) {
+	db := NewDatabase()
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		if err := db.SaveBillings(ctx, &resources.Billing{OrderID: fmt.Sprintf("order%d", i), Owner: "owner1", Type: accountv1.Consumption,
+			Namespace: "ns-a", Amount: 10, Time: start.Add(time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatalf("failed to save billings: %v", err)
+		}
+	}
+	if err := db.SaveBillings(ctx, &resources.Billing{OrderID: "order0", Owner: "owner1"}); err == nil {
+		t.Errorf("SaveBillings() duplicate order error = nil")
+	}
+
+	query := func(page, pageSize int) (*accountv1.BillingRecordQuery, error) {
+		q := &accountv1.BillingRecordQuery{Spec: accountv1.BillingRecordQuerySpec{
+			Page: page, PageSize: pageSize, Type: -1,
+			StartTime: metav1.NewTime(start), EndTime: metav1.NewTime(start.Add(24 * time.Hour)),
+		}}
+		return q, db.QueryBillingRecords(ctx, q, "owner1")
+	}
+	for _, tc := range []struct {
+		name         string
+		page         int
+		wantFirst    string
+		wantItems    int
+		wantPageLeng int
+	}{
+		{"first page", 1, "order4", 2, 3},
+		{"last page", 3, "order0", 1, 3},
+		{"page 0 is the first page", 0, "order4", 2, 3},
+		{"past the last page", 4, "", 0, 3},
+	} {
+		q, err := query(tc.page, 2)
+		if err != nil {
+			t.Fatalf("QueryBillingRecords(%s) error = %v", tc.name, err)
+		}
+		if len(q.Status.Items) != tc.wantItems || q.Status.PageLength != tc.wantPageLeng || q.Status.TotalCount != 5 || q.Status.DeductionAmount != 50 {
+			t.Errorf("QueryBillingRecords(%s) = %+v", tc.name, q.Status)
+			continue
+		}
+		if tc.wantItems != 0 && q.Status.Items[0].OrderID != tc.wantFirst {
+			t.Errorf("QueryBillingRecords(%s) first order = %s, want %s", tc.name, q.Status.Items[0].OrderID, tc.wantFirst)
+		}
+	}
+	if _, err := query(1, 0); err == nil {
+		t.Errorf("QueryBillingRecords() with page size 0 error = nil")
+	}
+}
+
+func TestDatabase_BillingStatus(t *testing.T) {
+	db := NewDatabase()
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := db.SaveBillings(ctx,
+		&resources.Billing{OrderID: "order1", Owner: "owner1", Type: accountv1.Consumption, Namespace: "ns-a", Amount: 10, Time: at, Status: resources.Unsettled},
+		&resources.Billing{OrderID: "order2", Owner: "owner1", Type: accountv1.Consumption, Namespace: "ns-b", Amount: 20, Time: at.Add(time.Hour), Status: resources.Unsettled},
+		&resources.Billing{OrderID: "order3", Owner: "owner2", Type: accountv1.Consumption, Namespace: "ns-c", Amount: 30, Time: at, Status: resources.Unsettled},
+	); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+	if err := db.UpdateBillingStatus(ctx, "order1", resources.Settled); err != nil {
+		t.Fatalf("failed to update billing status: %v", err)
+	}
+	handlers, err := db.GetUnsettingBillingHandler(ctx, "owner1")
+	if err != nil || len(handlers) != 1 || handlers[0].OrderID != "order2" || handlers[0].Amount != 20 {
+		t.Errorf("GetUnsettingBillingHandler() = %+v, %v, want order2", handlers, err)
+	}
+	if found, latest, err := db.GetBillingLastUpdateTime(ctx, "owner1", accountv1.Consumption); err != nil || !found || !latest.Equal(at.Add(time.Hour)) {
+		t.Errorf("GetBillingLastUpdateTime() = %v, %v, %v", found, latest, err)
+	}
+	end := at.Add(30 * time.Minute)
+	if namespaces, err := db.GetBillingHistoryNamespaces(ctx, &at, &end, int(accountv1.Consumption), "owner1"); err != nil || len(namespaces) != 1 || namespaces[0] != "ns-a" {
+		t.Errorf("GetBillingHistoryNamespaces() = %v, %v, want [ns-a]", namespaces, err)
+	}
+}
+
+func TestDatabase_SpendingCap(t *testing.T) {
+	db := NewDatabase()
+	ctx := context.Background()
+	if err := db.SetSpendingCap(ctx, &resources.SpendingCap{Owner: "owner1"}); err == nil {
+		t.Errorf("SetSpendingCap() without limit error = nil")
+	}
+	if err := db.SetSpendingCap(ctx, &resources.SpendingCap{Owner: "owner1", MonthlyLimit: 100}); err != nil {
+		t.Fatalf("failed to set spending cap: %v", err)
+	}
+	if spendingCap, err := db.GetSpendingCap(ctx, "owner1"); err != nil || spendingCap == nil || spendingCap.MonthlyLimit != 100 ||
+		spendingCap.GracePeriod() != resources.DefaultSpendingCapGracePeriod {
+		t.Errorf("GetSpendingCap() = %+v, %v", spendingCap, err)
+	}
+
+	now := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	periodStart, _ := resources.InvoicePeriod(now)
+	recommend := func() {
+		db.AddSuspendRecommendations(
+			resources.SuspendRecommendation{Owner: "owner1", PeriodStart: periodStart, SuspendAt: now},
+			resources.SuspendRecommendation{Owner: "owner2", PeriodStart: periodStart, SuspendAt: now.Add(time.Hour)},
+		)
+	}
+	recommend()
+	if due, err := db.GetDueSuspendRecommendations(ctx, now); err != nil || len(due) != 1 || due[0].Owner != "owner1" {
+		t.Errorf("GetDueSuspendRecommendations() = %+v, %v, want owner1", due, err)
+	}
+	if err := db.SetSpendingCap(ctx, &resources.SpendingCap{Owner: "owner1", MonthlyLimit: 200}); err != nil {
+		t.Fatalf("failed to raise spending cap: %v", err)
+	}
+	if due, err := db.GetDueSuspendRecommendations(ctx, now.Add(time.Hour)); err != nil || len(due) != 1 || due[0].Owner != "owner2" {
+		t.Errorf("GetDueSuspendRecommendations() after raising the cap = %+v, %v, want owner2", due, err)
+	}
+
+	recommend()
+	if err := db.DeleteSpendingCap(ctx, "owner1"); err != nil {
+		t.Fatalf("failed to delete spending cap: %v", err)
+	}
+	if spendingCap, err := db.GetSpendingCap(ctx, "owner1"); err != nil || spendingCap != nil {
+		t.Errorf("GetSpendingCap() after delete = %+v, %v", spendingCap, err)
+	}
+	for _, due := range mustDue(t, db, now.Add(time.Hour)) {
+		if due.Owner == "owner1" {
+			t.Errorf("GetDueSuspendRecommendations() after deleting the cap = %+v", due)
+		}
+	}
+}
+
+func mustDue(t *testing.T, db *Database, now time.Time) []resources.SuspendRecommendation {
+	t.Helper()
+	due, err := db.GetDueSuspendRecommendations(context.Background(), now)
+	if err != nil {
+		t.Fatalf("GetDueSuspendRecommendations() error = %v", err)
+	}
+	return due
+}
@@ -39,34 +39,157 @@ type Interface interface {
 }
 
 type CVM interface {
-	GetPendingStateInstance(regionUID string) (cvmMap map[string][]types.CVMBilling, err error)
-	SetDoneStateInstance(instanceIDs ...primitive.ObjectID) error
+	GetPendingStateInstance(ctx context.Context, regionUID string) (cvmMap map[string][]types.CVMBilling, err error)
+	SetDoneStateInstance(ctx context.Context, instanceIDs ...primitive.ObjectID) error
 }
 
+// Account is the full account database, consumers that only need part of it should depend on
+// the focused BillingStore, PropertyStore or MonitorStore instead.
 type Account interface {
 	//InitDB() error
-	GetBillingLastUpdateTime(owner string, _type common.Type) (bool, time.Time, error)
-	GetBillingHistoryNamespaceList(ns *accountv1.NamespaceBillingHistorySpec, owner string) ([]string, error)
-	GetBillingHistoryNamespaces(startTime, endTime *time.Time, billType int, owner string) ([]string, error)
-	SaveBillings(billing ...*resources.Billing) error
-	QueryBillingRecords(billingRecordQuery *accountv1.BillingRecordQuery, owner string) error
-	GetUnsettingBillingHandler(owner string) ([]resources.BillingHandler, error)
-	UpdateBillingStatus(orderID string, status resources.BillingStatus) error
-	GetUpdateTimeForCategoryAndPropertyFromMetering(category string, property string) (time.Time, error)
-	GetAllPricesMap() (map[string]resources.Price, error)
-	GetAllPayment() ([]resources.Billing, error)
-	InitDefaultPropertyTypeLS() error
-	SavePropertyTypes(types []resources.PropertyType) error
-	GetBillingCount(accountType common.Type, startTime, endTime time.Time) (count, amount int64, err error)
-	//GetNodePortAmount(owner string, endTime time.Time) (int64, error)
-	GenerateBillingData(startTime, endTime time.Time, prols *resources.PropertyTypeLS, namespaces []string, owner string) (orderID []string, amount int64, err error)
-	InsertMonitor(ctx context.Context, monitors ...*resources.Monitor) error
-	GetDistinctMonitorCombinations(startTime, endTime time.Time) ([]resources.Monitor, error)
-	DropMonitorCollectionsOlderThan(days int) error
+	BillingStore
+	PropertyStore
+	MonitorStore
+	InvoiceStore
+	DevboxStore
+	SpendingCapStore
 	Disconnect(ctx context.Context) error
 	Creator
 }
 
+type BillingStore interface {
+	GetBillingLastUpdateTime(ctx context.Context, owner string, _type common.Type) (bool, time.Time, error)
+	GetBillingHistoryNamespaceList(ctx context.Context, ns *accountv1.NamespaceBillingHistorySpec, owner string) ([]string, error)
+	GetBillingHistoryNamespaces(ctx context.Context, startTime, endTime *time.Time, billType int, owner string) ([]string, error)
+	SaveBillings(ctx context.Context, billing ...*resources.Billing) error
+	QueryBillingRecords(ctx context.Context, billingRecordQuery *accountv1.BillingRecordQuery, owner string) error
+	GetUnsettingBillingHandler(ctx context.Context, owner string) ([]resources.BillingHandler, error)
+	UpdateBillingStatus(ctx context.Context, orderID string, status resources.BillingStatus) error
+	GetAllPayment(ctx context.Context) ([]resources.Billing, error)
+	GetBillingCount(ctx context.Context, accountType common.Type, startTime, endTime time.Time) (count, amount int64, err error)
+	//GetNodePortAmount(owner string, endTime time.Time) (int64, error)
+	GenerateBillingData(ctx context.Context, startTime, endTime time.Time, prols *resources.PropertyTypeLS, namespaces []string, owner string) (orderID []string, amount int64, err error)
+	// GetCostBreakdown groups the consumption of owner, resource names are resolved with prols, the property types used for billing.
+	GetCostBreakdown(ctx context.Context, owner, namespace string, startTime, endTime time.Time, prols *resources.PropertyTypeLS, groupBy CostGroupBy) ([]CostBreakdownItem, error)
+	// WatchBillings streams billing inserts/updates of owner (all owners if empty) until ctx is done,
+	// resuming after the last acknowledged event of the previous watch of the same owner. Every event
+	// must be acknowledged with Ack before the next one is sent, see BillingEvent.
+	WatchBillings(ctx context.Context, owner string) (<-chan BillingEvent, error)
+	// GetBillingUsage re-aggregates the usage of a past billing window the same way as GenerateBillingData and
+	// returns it with the usage of the consumption billing stored for the window. Only the properties priced in prols
+	// are returned, and apps whose usage prices at zero are left out of expected as no billing is generated for them.
+	GetBillingUsage(ctx context.Context, startTime, endTime time.Time, prols *resources.PropertyTypeLS, namespaces []string, owner string) (expected map[BillingUsageKey]int64, billed map[BillingUsageKey]BillingUsage, err error)
+	// GetBillingAmountSeries sums the consumption billing of all owners per namespace, app type and hour in [startTime, endTime).
+	GetBillingAmountSeries(ctx context.Context, startTime, endTime time.Time) ([]BillingAmountSample, error)
+}
+
+type InvoiceStore interface {
+	// GenerateInvoice assembles the invoice of owner for the month of period from the consumption billing,
+	// regenerating an existing invoice replaces its items and keeps its sequence number.
+	GenerateInvoice(ctx context.Context, owner string, period time.Time) (*resources.Invoice, error)
+	GetInvoice(ctx context.Context, owner string, period time.Time) (*resources.Invoice, error)
+}
+
+// DevboxStore keeps the devbox usage records. The records are storage only, GenerateBillingData does not bill them
+// until a producer writes them.
+type DevboxStore interface {
+	SaveDevboxUsage(ctx context.Context, usages ...*resources.DevboxUsage) error
+	// AggregateDevboxUsage summarizes the usage of each devbox of owner in [startTime, endTime).
+	AggregateDevboxUsage(ctx context.Context, owner string, startTime, endTime time.Time) ([]resources.DevboxUsageSummary, error)
+}
+
+type SpendingCapStore interface {
+	SetSpendingCap(ctx context.Context, spendingCap *resources.SpendingCap) error
+	// GetSpendingCap returns nil if owner has no spending cap.
+	GetSpendingCap(ctx context.Context, owner string) (*resources.SpendingCap, error)
+	DeleteSpendingCap(ctx context.Context, owner string) error
+	// EvaluateSpendingCap sums the consumption of owner in the month of at and records a suspend recommendation
+	// once it exceeds the spending cap. It returns nil if owner has no cap or is within it.
+	EvaluateSpendingCap(ctx context.Context, owner string, at time.Time) (*resources.SuspendRecommendation, error)
+	// GetDueSuspendRecommendations returns the recommendations whose grace period has passed at now.
+	GetDueSuspendRecommendations(ctx context.Context, now time.Time) ([]resources.SuspendRecommendation, error)
+}
+
+type PropertyStore interface {
+	GetAllPricesMap(ctx context.Context) (map[string]resources.Price, error)
+	InitDefaultPropertyTypeLS(ctx context.Context) error
+	SavePropertyTypes(ctx context.Context, types []resources.PropertyType) error
+}
+
+type MonitorStore interface {
+	InsertMonitor(ctx context.Context, monitors ...*resources.Monitor) error
+	GetDistinctMonitorCombinations(ctx context.Context, startTime, endTime time.Time) ([]resources.Monitor, error)
+	DropMonitorCollectionsOlderThan(ctx context.Context, days int) error
+	GetUpdateTimeForCategoryAndPropertyFromMetering(ctx context.Context, category string, property string) (time.Time, error)
+}
+
+type BillingEventType string
+
+const (
+	BillingEventInsert  BillingEventType = "insert"
+	BillingEventUpdate  BillingEventType = "update"
+	BillingEventReplace BillingEventType = "replace"
+)
+
+// BillingEvent is a change of the billing collection, the channel is closed after an event with Err is sent.
+type BillingEvent struct {
+	Type    BillingEventType
+	Billing resources.Billing
+	Err     error
+	ack     chan struct{}
+}
+
+// NewBillingEvent returns an event that is acknowledged on ack, for the WatchBillings implementations.
+func NewBillingEvent(tp BillingEventType, billing resources.Billing, ack chan struct{}) BillingEvent {
+	return BillingEvent{Type: tp, Billing: billing, ack: ack}
+}
+
+// Ack acknowledges that the event is processed. The resume token of the event is only persisted after the ack,
+// so an event that was not acknowledged before a restart is delivered again: delivery is at-least-once.
+func (e BillingEvent) Ack() {
+	select {
+	case e.ack <- struct{}{}:
+	default:
+	}
+}
+
+type CostGroupBy string
+
+const (
+	CostGroupByAppType  CostGroupBy = "appType"
+	CostGroupByAppName  CostGroupBy = "appName"
+	CostGroupByResource CostGroupBy = "resource"
+)
+
+// CostBreakdownItem is the consumption amount of one group, only the fields of the group key are set.
+type CostBreakdownItem struct {
+	AppType  string `json:"appType,omitempty" bson:"app_type,omitempty"`
+	AppName  string `json:"appName,omitempty" bson:"app_name,omitempty"`
+	Resource string `json:"resource,omitempty" bson:"resource,omitempty"`
+	Amount   int64  `json:"amount" bson:"amount"`
+}
+
+// BillingAmountSample is the consumption amount of a namespace and app type billed at Time, truncated to the hour.
+type BillingAmountSample struct {
+	Namespace string    `json:"namespace" bson:"namespace"`
+	AppType   string    `json:"appType" bson:"app_type"`
+	Time      time.Time `json:"time" bson:"time"`
+	Amount    int64     `json:"amount" bson:"amount"`
+}
+
+// BillingUsageKey is the key of the usage of one property of a namespace and app type in a billing window.
+type BillingUsageKey struct {
+	Namespace string
+	AppType   uint8
+	Property  uint8
+}
+
+// BillingUsage is the used value of one property and the amount charged for it.
+type BillingUsage struct {
+	Used   int64
+	Amount int64
+}
+
 type BillingRecordQuery struct {
 	Page      int         `json:"page"`
 	PageSize  int         `json:"pageSize"`
@@ -78,12 +201,28 @@ type BillingRecordQuery struct {
 	AppType   string      `json:"appType,omitempty"`
 }
 
-type Traffic interface {
-	GetTrafficSentBytes(startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error)
-	GetTrafficRecvBytes(startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error)
+// Traffic is kept for the existing consumers of the traffic database.
+type Traffic = TrafficStore
+
+type TrafficStore interface {
+	GetTrafficSentBytes(ctx context.Context, startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error)
+	GetTrafficRecvBytes(ctx context.Context, startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error)
+
+	GetPodTrafficSentBytes(ctx context.Context, startTime, endTime time.Time, namespace string, name string) (int64, error)
+	GetPodTrafficRecvBytes(ctx context.Context, startTime, endTime time.Time, namespace string, name string) (int64, error)
+
+	// GetTrafficTTL returns the expireAfterSeconds of the traffic time series, zero means never expire.
+	GetTrafficTTL(ctx context.Context) (time.Duration, error)
+	SetTrafficTTL(ctx context.Context, ttl time.Duration) error
+	CreateTrafficIndexes(ctx context.Context) error
+	// ArchiveTrafficBefore copies traffic older than before into the archive collection, returns the count of newly archived documents.
+	ArchiveTrafficBefore(ctx context.Context, before time.Time) (int64, error)
 
-	GetPodTrafficSentBytes(startTime, endTime time.Time, namespace string, name string) (int64, error)
-	GetPodTrafficRecvBytes(startTime, endTime time.Time, namespace string, name string) (int64, error)
+	// InsertNodePortTraffic stores the node agent records of the NodePort traffic, they are not billed yet.
+	InsertNodePortTraffic(ctx context.Context, records ...*resources.NodePortTraffic) error
+	// GetNodePortTraffic sums the NodePort traffic per devbox of namespace (all namespaces if empty) in [startTime, endTime).
+	GetNodePortTraffic(ctx context.Context, namespace string, startTime, endTime time.Time) ([]resources.NodePortTrafficSummary, error)
+	CreateNodePortTrafficIfNotExist(ctx context.Context) error
 }
 
 type AccountV2 interface {
@@ -111,9 +250,15 @@ type AccountV2 interface {
 }
 
 type Creator interface {
-	CreateBillingIfNotExist() error
+	CreateBillingIfNotExist(ctx context.Context) error
+	CreateInvoiceIfNotExist(ctx context.Context) error
+	CreateDevboxUsageIfNotExist(ctx context.Context) error
+	CreateSpendingCapIfNotExist(ctx context.Context) error
+	// EnsureIndexes creates the missing indexes of the existing collections, it is safe to call on every startup.
+	// Building an index on a large collection can take long, callers should not block on it.
+	EnsureIndexes(ctx context.Context) error
 	//suffix by day, eg： monitor_20200101
-	CreateMonitorTimeSeriesIfNotExist(collTime time.Time) error
+	CreateMonitorTimeSeriesIfNotExist(ctx context.Context, collTime time.Time) error
 }
 
 type MeteringOwnerTimeResult struct {
@@ -17,7 +17,6 @@ package mongo
 import (
 	"context"
 	"fmt"
-	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -42,11 +41,13 @@ import (
 )
 
 const (
-	EnvAccountDBName = "ACCOUNT_DB_NAME"
-	EnvTrafficDBName = "TRAFFIC_DB_NAME"
-	EnvCVMDBName     = "CVM_DB_NAME"
-	EnvCVMConn       = "CVM_DB_CONN"
-	EnvTrafficConn   = "TRAFFIC_CONN"
+	EnvAccountDBName      = "ACCOUNT_DB_NAME"
+	EnvTrafficDBName      = "TRAFFIC_DB_NAME"
+	EnvCVMDBName          = "CVM_DB_NAME"
+	EnvCVMConn            = "CVM_DB_CONN"
+	EnvTrafficConn        = "TRAFFIC_CONN"
+	EnvTrafficArchiveConn = "TRAFFIC_ARCHIVE_CONN"
+	EnvNodePortTraffic    = "NODEPORT_TRAFFIC_CONN"
 )
 
 const (
@@ -58,11 +59,19 @@ const (
 	DefaultMeteringConn   = "metering"
 	DefaultMonitorConn    = "monitor"
 	DefaultBillingConn    = "billing"
+	DefaultBillingWatch   = "billing_watch"
+	DefaultInvoiceConn    = "invoice"
+	DefaultCounterConn    = "counter"
+	DefaultDevboxUsage    = "devbox_usage"
+	DefaultSpendingCap    = "spending_cap"
+	DefaultSuspendRecomm  = "suspend_recommendation"
 	DefaultUserConn       = "user"
 	DefaultPricesConn     = "prices"
 	DefaultPropertiesConn = "properties"
 	//TODO fix
-	DefaultTrafficConn = "traffic"
+	DefaultTrafficConn        = "traffic"
+	DefaultTrafficArchiveConn = "traffic_archive"
+	DefaultNodePortTraffic    = "nodeport_traffic"
 )
 
 const DefaultRetentionDay = 30
@@ -83,9 +92,19 @@ type mongoDB struct {
 	MonitorConnPrefix string
 	MeteringConn      string
 	BillingConn       string
+	BillingWatchConn  string
+	InvoiceConn       string
+	CounterConn       string
+	DevboxUsageConn   string
+	SpendingCapConn   string
+	SuspendConn       string
 	PricesConn        string
 	PropertiesConn    string
 	TrafficConn       string
+	TrafficArchive    string
+	NodePortTraffic   string
+	OperationTimeout  time.Duration
+	IndexBuildTimeout time.Duration
 }
 
 type AccountBalanceSpecBSON struct {
@@ -99,7 +118,9 @@ func (m *mongoDB) Disconnect(ctx context.Context) error {
 	return m.Client.Disconnect(ctx)
 }
 
-func (m *mongoDB) GetBillingLastUpdateTime(owner string, _type common.Type) (bool, time.Time, error) {
+func (m *mongoDB) GetBillingLastUpdateTime(ctx context.Context, owner string, _type common.Type) (bool, time.Time, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	// skip cvm billing time
 	filter := bson.M{
 		"owner": owner,
@@ -110,7 +131,7 @@ func (m *mongoDB) GetBillingLastUpdateTime(owner string, _type common.Type) (boo
 	}
 	findOneOptions := options.FindOne().SetSort(bson.D{primitive.E{Key: "time", Value: -1}})
 	var result bson.M
-	err := m.getBillingCollection().FindOne(context.Background(), filter, findOneOptions).Decode(&result)
+	err := m.getBillingCollection().FindOne(ctx, filter, findOneOptions).Decode(&result)
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -126,7 +147,9 @@ func (m *mongoDB) GetBillingLastUpdateTime(owner string, _type common.Type) (boo
 	return false, time.Time{}, fmt.Errorf("failed to convert time field to primitive.DateTime: %v", result["time"])
 }
 
-func (m *mongoDB) GetUnsettingBillingHandler(owner string) ([]resources.BillingHandler, error) {
+func (m *mongoDB) GetUnsettingBillingHandler(ctx context.Context, owner string) ([]resources.BillingHandler, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	filter := bson.M{
 		"owner": owner,
 		"status": bson.M{
@@ -136,13 +159,13 @@ func (m *mongoDB) GetUnsettingBillingHandler(owner string) ([]resources.BillingH
 		},
 	}
 	findOptions := options.Find()
-	cur, err := m.getBillingCollection().Find(context.Background(), filter, findOptions)
+	cur, err := m.getBillingCollection().Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, fmt.Errorf("find error: %v", err)
 	}
-	defer cur.Close(context.Background())
+	defer cur.Close(ctx)
 	var results []resources.BillingHandler
-	for cur.Next(context.Background()) {
+	for cur.Next(ctx) {
 		var result resources.BillingHandler
 		if err := cur.Decode(&result); err != nil {
 			return nil, fmt.Errorf("decode error: %v", err)
@@ -155,7 +178,9 @@ func (m *mongoDB) GetUnsettingBillingHandler(owner string) ([]resources.BillingH
 	return results, nil
 }
 
-func (m *mongoDB) UpdateBillingStatus(orderID string, status resources.BillingStatus) error {
+func (m *mongoDB) UpdateBillingStatus(ctx context.Context, orderID string, status resources.BillingStatus) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	// create a query filter
 	filter := bson.M{"order_id": orderID}
 	update := bson.M{
@@ -163,14 +188,16 @@ func (m *mongoDB) UpdateBillingStatus(orderID string, status resources.BillingSt
 			"status": status,
 		},
 	}
-	_, err := m.getBillingCollection().UpdateOne(context.Background(), filter, update)
+	_, err := m.getBillingCollection().UpdateOne(ctx, filter, update)
 	if err != nil {
 		return fmt.Errorf("update error: %v", err)
 	}
 	return nil
 }
 
-func (m *mongoDB) GetBillingHistoryNamespaces(startTime, endTime *time.Time, billType int, owner string) ([]string, error) {
+func (m *mongoDB) GetBillingHistoryNamespaces(ctx context.Context, startTime, endTime *time.Time, billType int, owner string) ([]string, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	filter := bson.M{
 		"owner": owner,
 	}
@@ -189,13 +216,13 @@ func (m *mongoDB) GetBillingHistoryNamespaces(startTime, endTime *time.Time, bil
 		{{Key: "$group", Value: bson.D{{Key: "_id", Value: nil}, {Key: "namespaces", Value: bson.D{{Key: "$addToSet", Value: "$namespace"}}}}}},
 	}
 
-	cur, err := m.getBillingCollection().Aggregate(context.Background(), pipeline)
+	cur, err := m.getBillingCollection().Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
-	defer cur.Close(context.Background())
+	defer cur.Close(ctx)
 
-	if !cur.Next(context.Background()) {
+	if !cur.Next(ctx) {
 		return []string{}, nil
 	}
 
@@ -208,7 +235,9 @@ func (m *mongoDB) GetBillingHistoryNamespaces(startTime, endTime *time.Time, bil
 	return result.Namespaces, nil
 }
 
-func (m *mongoDB) GetBillingHistoryNamespaceList(nsHistorySpec *accountv1.NamespaceBillingHistorySpec, owner string) ([]string, error) {
+func (m *mongoDB) GetBillingHistoryNamespaceList(ctx context.Context, nsHistorySpec *accountv1.NamespaceBillingHistorySpec, owner string) ([]string, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	filter := bson.M{
 		"owner": owner,
 	}
@@ -227,13 +256,13 @@ func (m *mongoDB) GetBillingHistoryNamespaceList(nsHistorySpec *accountv1.Namesp
 		{{Key: "$group", Value: bson.D{{Key: "_id", Value: nil}, {Key: "namespaces", Value: bson.D{{Key: "$addToSet", Value: "$namespace"}}}}}},
 	}
 
-	cur, err := m.getBillingCollection().Aggregate(context.Background(), pipeline)
+	cur, err := m.getBillingCollection().Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
-	defer cur.Close(context.Background())
+	defer cur.Close(ctx)
 
-	if !cur.Next(context.Background()) {
+	if !cur.Next(ctx) {
 		return []string{}, nil
 	}
 
@@ -246,12 +275,14 @@ func (m *mongoDB) GetBillingHistoryNamespaceList(nsHistorySpec *accountv1.Namesp
 	return result.Namespaces, nil
 }
 
-func (m *mongoDB) SaveBillings(billing ...*resources.Billing) error {
+func (m *mongoDB) SaveBillings(ctx context.Context, billing ...*resources.Billing) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	billings := make([]interface{}, len(billing))
 	for i, b := range billing {
 		billings[i] = b
 	}
-	_, err := m.getBillingCollection().InsertMany(context.Background(), billings)
+	_, err := m.getBillingCollection().InsertMany(ctx, billings)
 	return err
 }
 
@@ -269,7 +300,9 @@ func (m *mongoDB) InsertMonitor(ctx context.Context, monitors ...*resources.Moni
 	return err
 }
 
-func (m *mongoDB) GetDistinctMonitorCombinations(startTime, endTime time.Time) ([]resources.Monitor, error) {
+func (m *mongoDB) GetDistinctMonitorCombinations(ctx context.Context, startTime, endTime time.Time) ([]resources.Monitor, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	pipeline := mongo.Pipeline{
 		{{Key: "$match", Value: bson.M{
 			"time": bson.M{
@@ -291,23 +324,23 @@ func (m *mongoDB) GetDistinctMonitorCombinations(startTime, endTime time.Time) (
 			"type":     "$_id.type",
 		}}},
 	}
-	cursor, err := m.getMonitorCollection(startTime).Aggregate(context.Background(), pipeline)
+	cursor, err := m.getMonitorCollection(startTime).Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, fmt.Errorf("aggregate error: %v", err)
 	}
-	defer cursor.Close(context.Background())
-	if !cursor.Next(context.Background()) {
+	defer cursor.Close(ctx)
+	if !cursor.Next(ctx) {
 		return nil, nil
 	}
 	var monitors []resources.Monitor
-	if err := cursor.All(context.Background(), &monitors); err != nil {
+	if err := cursor.All(ctx, &monitors); err != nil {
 		return nil, fmt.Errorf("cursor error: %v", err)
 	}
 	return monitors, nil
 }
 
-func (m *mongoDB) GetAllPricesMap() (map[string]resources.Price, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (m *mongoDB) GetAllPricesMap(ctx context.Context) (map[string]resources.Price, error) {
+	ctx, cancel := m.operationContext(ctx)
 	defer cancel()
 	cursor, err := m.getPricesCollection().Find(ctx, bson.M{})
 	if err != nil {
@@ -336,26 +369,28 @@ func (m *mongoDB) GetAllPricesMap() (map[string]resources.Price, error) {
 	return pricesMap, nil
 }
 
-func (m *mongoDB) GetAllPayment() ([]resources.Billing, error) {
+func (m *mongoDB) GetAllPayment(ctx context.Context) ([]resources.Billing, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	filter := bson.M{
 		"type":           1,
 		"payment.amount": bson.M{"$gt": 0},
 	}
 
-	cursor, err := m.getBillingCollection().Find(context.Background(), filter)
+	cursor, err := m.getBillingCollection().Find(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("get all payment error: %v", err)
 	}
 
 	var payments []resources.Billing
-	if err = cursor.All(context.Background(), &payments); err != nil {
+	if err = cursor.All(ctx, &payments); err != nil {
 		return nil, fmt.Errorf("get all payment error: %v", err)
 	}
 	return payments, nil
 }
 
-func (m *mongoDB) InitDefaultPropertyTypeLS() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (m *mongoDB) InitDefaultPropertyTypeLS(ctx context.Context) error {
+	ctx, cancel := m.operationContext(ctx)
 	defer cancel()
 	cursor, err := m.getPropertiesCollection().Find(ctx, bson.M{})
 	if err != nil {
@@ -366,17 +401,49 @@ func (m *mongoDB) InitDefaultPropertyTypeLS() error {
 		return fmt.Errorf("get all prices error: %v", err)
 	}
 	if len(properties) != 0 {
+		missing, err := resources.MissingDevboxPropertyTypes(properties)
+		if err != nil {
+			return fmt.Errorf("failed to allocate devbox properties: %w", err)
+		}
+		// the devbox properties are saved so that their enums stay the same after a restart,
+		// the unique name index makes a concurrent start saving the same property first win
+		if len(missing) != 0 {
+			if _, err := m.getPropertiesCollection().Indexes().CreateMany(ctx, propertiesIndexes); err != nil {
+				return fmt.Errorf("failed to create properties indexes: %w", err)
+			}
+		}
+		for _, prop := range missing {
+			_, err := m.getPropertiesCollection().UpdateOne(ctx, bson.M{"name": prop.Name}, bson.M{"$setOnInsert": prop}, options.Update().SetUpsert(true))
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to save property %s: %w", prop.Name, err)
+			}
+			logger.Warn("property %s is not in the property list, added with enum %d and no price", prop.Name, prop.Enum)
+		}
+		if len(missing) != 0 {
+			properties = nil
+			if cursor, err = m.getPropertiesCollection().Find(ctx, bson.M{}); err != nil {
+				return fmt.Errorf("get all prices error: %v", err)
+			}
+			if err = cursor.All(ctx, &properties); err != nil {
+				return fmt.Errorf("get all prices error: %v", err)
+			}
+		}
 		resources.DefaultPropertyTypeLS = resources.NewPropertyTypeLS(properties)
 	}
 	return nil
 }
 
-func (m *mongoDB) SavePropertyTypes(types []resources.PropertyType) error {
+func (m *mongoDB) SavePropertyTypes(ctx context.Context, types []resources.PropertyType) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	tps := make([]interface{}, len(types))
 	for i, b := range types {
 		tps[i] = b
 	}
-	_, err := m.getPropertiesCollection().InsertMany(context.Background(), tps)
+	_, err := m.getPropertiesCollection().InsertMany(ctx, tps)
 	return err
 }
 
@@ -389,7 +456,90 @@ func (m *mongoDB) SavePropertyTypes(types []resources.PropertyType) error {
 		Name:     resourceMap[name].Name(),
 	})
 */
-func (m *mongoDB) GenerateBillingData(startTime, endTime time.Time, prols *resources.PropertyTypeLS, namespaces []string, owner string) (orderID []string, amount int64, err error) {
+func (m *mongoDB) GenerateBillingData(ctx context.Context, startTime, endTime time.Time, prols *resources.PropertyTypeLS, namespaces []string, owner string) (orderID []string, amount int64, err error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	billings, err := m.calculateBillings(ctx, startTime, endTime, prols, namespaces, owner)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range billings {
+		id, err := gonanoid.New(12)
+		if err != nil {
+			return nil, 0, fmt.Errorf("generate billing id error: %v", err)
+		}
+		billings[i].OrderID = id
+		// Insert the billing document
+		_, err = m.getBillingCollection().InsertOne(ctx, billings[i])
+		if err != nil {
+			return nil, 0, fmt.Errorf("insert error: %v", err)
+		}
+		amount += billings[i].Amount
+		orderID = append(orderID, id)
+	}
+	return orderID, amount, nil
+}
+
+// appUsage is the used values of one app in a billing window, before pricing.
+type appUsage struct {
+	namespace string
+	appType   uint8
+	appCost   resources.AppCost
+}
+
+// calculateBillings prices the usage of the window into one billing per namespace and app type without saving them.
+func (m *mongoDB) calculateBillings(ctx context.Context, startTime, endTime time.Time, prols *resources.PropertyTypeLS, namespaces []string, owner string) ([]resources.Billing, error) {
+	usages, err := m.aggregateUsage(ctx, startTime, endTime, prols, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	// the used values of the whole window are priced together, tiers apply to the usage of the owner
+	appCosts := make([]*resources.AppCost, len(usages))
+	for i := range usages {
+		appCosts[i] = &usages[i].appCost
+	}
+	resources.PriceAppCosts(prols, owner, appCosts)
+
+	var appCostsMap = make(map[string]map[uint8][]resources.AppCost)
+	// map[ns/type]int64
+	var nsTypeAmount = make(map[string]int64)
+	for _, usage := range usages {
+		if usage.appCost.Amount == 0 {
+			continue
+		}
+		if _, ok := appCostsMap[usage.namespace]; !ok {
+			appCostsMap[usage.namespace] = make(map[uint8][]resources.AppCost)
+		}
+		nsTypeAmount[usage.namespace+strconv.Itoa(int(usage.appType))] += usage.appCost.Amount
+		appCostsMap[usage.namespace][usage.appType] = append(appCostsMap[usage.namespace][usage.appType], usage.appCost)
+	}
+
+	var billings []resources.Billing
+	for ns, appCostMap := range appCostsMap {
+		for tp, appCost := range appCostMap {
+			amount := nsTypeAmount[ns+strconv.Itoa(int(tp))]
+			if amount == 0 {
+				continue
+			}
+			billings = append(billings, resources.Billing{
+				Type:      accountv1.Consumption,
+				Namespace: ns,
+				AppType:   tp,
+				AppCosts:  appCost,
+				Amount:    amount,
+				Owner:     owner,
+				Time:      endTime,
+				Status:    resources.Settled,
+			})
+		}
+	}
+	return billings, nil
+}
+
+// aggregateUsage aggregates the monitors of the window into the used values of each app,
+// prols only provides the properties and how they are aggregated.
+func (m *mongoDB) aggregateUsage(ctx context.Context, startTime, endTime time.Time, prols *resources.PropertyTypeLS, namespaces []string) ([]*appUsage, error) {
 	minutes := endTime.Sub(startTime).Minutes()
 
 	groupStage := bson.D{
@@ -458,17 +608,15 @@ func (m *mongoDB) GenerateBillingData(startTime, endTime time.Time, prols *resou
 		{{Key: "$project", Value: projectStage}},
 	}
 
-	cursor, err := m.getMonitorCollection(startTime).Aggregate(context.Background(), pipeline)
+	cursor, err := m.getMonitorCollection(startTime).Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, 0, fmt.Errorf("aggregate error: %v", err)
+		return nil, fmt.Errorf("aggregate error: %v", err)
 	}
-	defer cursor.Close(context.Background())
+	defer cursor.Close(ctx)
 
-	var appCostsMap = make(map[string]map[uint8][]resources.AppCost)
-	// map[ns/type]int64
-	var nsTypeAmount = make(map[string]int64)
+	var usages []*appUsage
 
-	for cursor.Next(context.Background()) {
+	for cursor.Next(ctx) {
 		var result struct {
 			Type      uint8                 `bson:"type"`
 			Namespace string                `bson:"category"`
@@ -478,79 +626,29 @@ func (m *mongoDB) GenerateBillingData(startTime, endTime time.Time, prols *resou
 
 		err := cursor.Decode(&result)
 		if err != nil {
-			return nil, 0, fmt.Errorf("decode error: %v", err)
+			return nil, fmt.Errorf("decode error: %v", err)
 		}
 
 		//TODO delete
 		//logger.Info("generate billing data", "result", result)
 
-		if _, ok := appCostsMap[result.Namespace]; !ok {
-			appCostsMap[result.Namespace] = make(map[uint8][]resources.AppCost)
-		}
-		if _, ok := appCostsMap[result.Namespace][result.Type]; !ok {
-			appCostsMap[result.Namespace][result.Type] = make([]resources.AppCost, 0)
-		}
-		appCost := resources.AppCost{
-			Used:       result.Used,
-			Name:       result.Name,
-			UsedAmount: make(map[uint8]int64),
-		}
-		// Calculate the amount and set the used value
-		for property := range result.Used {
-			if prop, ok := prols.EnumMap[property]; ok {
-				if prop.UnitPrice > 0 {
-					appCost.UsedAmount[property] = int64(math.Ceil(float64(result.Used[property]) * prop.UnitPrice))
-					appCost.Amount += appCost.UsedAmount[property]
-				}
-			}
-		}
-		if appCost.Amount == 0 {
-			continue
-		}
-		nsTypeAmount[result.Namespace+strconv.Itoa(int(result.Type))] += appCost.Amount
-		appCostsMap[result.Namespace][result.Type] = append(appCostsMap[result.Namespace][result.Type], appCost)
-	}
-
-	for ns, appCostMap := range appCostsMap {
-		for tp, appCost := range appCostMap {
-			amountt := nsTypeAmount[ns+strconv.Itoa(int(tp))]
-			if amountt == 0 {
-				continue
-			}
-			id, err := gonanoid.New(12)
-			if err != nil {
-				return nil, 0, fmt.Errorf("generate billing id error: %v", err)
-			}
-			billing := resources.Billing{
-				OrderID:   id,
-				Type:      accountv1.Consumption,
-				Namespace: ns,
-				AppType:   tp,
-				AppCosts:  appCost,
-				Amount:    amountt,
-				Owner:     owner,
-				Time:      endTime,
-				Status:    resources.Settled,
-			}
-			amount += amountt
-			orderID = append(orderID, id)
-			// Insert the billing document
-			_, err = m.getBillingCollection().InsertOne(context.Background(), billing)
-			if err != nil {
-				return nil, 0, fmt.Errorf("insert error: %v", err)
-			}
-			//TODO delete
-			//logger.Info("generate billing data", "billing", billing)
-		}
+		usages = append(usages, &appUsage{
+			namespace: result.Namespace,
+			appType:   result.Type,
+			appCost:   resources.AppCost{Used: result.Used, Name: result.Name},
+		})
 	}
 
 	if err = cursor.Err(); err != nil {
-		return nil, 0, fmt.Errorf("cursor error: %v", err)
+		return nil, fmt.Errorf("cursor error: %v", err)
 	}
-	return orderID, amount, nil
+
+	return usages, nil
 }
 
-func (m *mongoDB) GetUpdateTimeForCategoryAndPropertyFromMetering(category string, property string) (time.Time, error) {
+func (m *mongoDB) GetUpdateTimeForCategoryAndPropertyFromMetering(ctx context.Context, category string, property string) (time.Time, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	filter := bson.M{"category": category, "property": property}
 	// sort by time desc
 	opts := options.FindOne().SetSort(bson.D{primitive.E{Key: "time", Value: -1}})
@@ -558,7 +656,7 @@ func (m *mongoDB) GetUpdateTimeForCategoryAndPropertyFromMetering(category strin
 	var result struct {
 		Time time.Time `bson:"time"`
 	}
-	err := m.getMeteringCollection().FindOne(context.Background(), filter, opts).Decode(&result)
+	err := m.getMeteringCollection().FindOne(ctx, filter, opts).Decode(&result)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			// No documents match the filter. Handle this case accordingly.
@@ -569,7 +667,7 @@ func (m *mongoDB) GetUpdateTimeForCategoryAndPropertyFromMetering(category strin
 	return result.Time, nil
 }
 
-func (m *mongoDB) queryBillingRecordsByOrderID(billingRecordQuery *accountv1.BillingRecordQuery, owner string) error {
+func (m *mongoDB) queryBillingRecordsByOrderID(ctx context.Context, billingRecordQuery *accountv1.BillingRecordQuery, owner string) error {
 	if billingRecordQuery.Spec.OrderID == "" {
 		return fmt.Errorf("order id is empty")
 	}
@@ -581,7 +679,8 @@ func (m *mongoDB) queryBillingRecordsByOrderID(billingRecordQuery *accountv1.Bil
 		}},
 	}
 	var billingRecords []accountv1.BillingRecordQueryItem
-	ctx := context.Background()
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 
 	cursor, err := billingColl.Aggregate(ctx, bson.A{matchStage})
 	if err != nil {
@@ -638,15 +737,23 @@ func (m *mongoDB) queryBillingRecordsByOrderID(billingRecordQuery *accountv1.Bil
 	return nil
 }
 
-func (m *mongoDB) QueryBillingRecords(billingRecordQuery *accountv1.BillingRecordQuery, owner string) (err error) {
+func (m *mongoDB) QueryBillingRecords(ctx context.Context, billingRecordQuery *accountv1.BillingRecordQuery, owner string) (err error) {
 	if billingRecordQuery.Spec.OrderID != "" {
-		return m.queryBillingRecordsByOrderID(billingRecordQuery, owner)
+		return m.queryBillingRecordsByOrderID(ctx, billingRecordQuery, owner)
 	}
 	if owner == "" {
 		return fmt.Errorf("owner is empty")
 	}
+	if billingRecordQuery.Spec.PageSize < 1 {
+		return fmt.Errorf("page size must be positive")
+	}
+	// the first page is returned for a page below 1
+	page := billingRecordQuery.Spec.Page
+	if page < 1 {
+		page = 1
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := m.operationContext(ctx)
 	defer cancel()
 
 	billingColl := m.getBillingCollection()
@@ -674,7 +781,7 @@ func (m *mongoDB) QueryBillingRecords(billingRecordQuery *accountv1.BillingRecor
 	pipeline := bson.A{
 		matchStage,
 		bson.D{primitive.E{Key: "$sort", Value: bson.D{primitive.E{Key: "time", Value: -1}}}},
-		bson.D{primitive.E{Key: "$skip", Value: (billingRecordQuery.Spec.Page - 1) * billingRecordQuery.Spec.PageSize}},
+		bson.D{primitive.E{Key: "$skip", Value: (page - 1) * billingRecordQuery.Spec.PageSize}},
 		bson.D{primitive.E{Key: "$limit", Value: billingRecordQuery.Spec.PageSize}},
 	}
 
@@ -847,7 +954,9 @@ func (m *mongoDB) QueryBillingRecords(billingRecordQuery *accountv1.BillingRecor
 //
 //}
 
-func (m *mongoDB) GetBillingCount(accountType common.Type, startTime, endTime time.Time) (count, amount int64, err error) {
+func (m *mongoDB) GetBillingCount(ctx context.Context, accountType common.Type, startTime, endTime time.Time) (count, amount int64, err error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	pipeline := bson.A{
 		bson.M{
 			"$match": bson.M{
@@ -867,18 +976,18 @@ func (m *mongoDB) GetBillingCount(accountType common.Type, startTime, endTime ti
 		},
 	}
 
-	cursor, err := m.getBillingCollection().Aggregate(context.Background(), pipeline)
+	cursor, err := m.getBillingCollection().Aggregate(ctx, pipeline)
 	if err != nil {
 		return 0, 0, err
 	}
-	defer cursor.Close(context.Background())
+	defer cursor.Close(ctx)
 
 	var result struct {
 		Count  int64 `bson:"count"`
 		Amount int64 `bson:"amount"`
 	}
 
-	if cursor.Next(context.Background()) {
+	if cursor.Next(ctx) {
 		if err := cursor.Decode(&result); err != nil {
 			return 0, 0, fmt.Errorf("failed to decode aggregation result: %w", err)
 		}
@@ -913,32 +1022,19 @@ func (m *mongoDB) getPropertiesCollection() *mongo.Collection {
 	return m.Client.Database(m.AccountDB).Collection(m.PropertiesConn)
 }
 
-func (m *mongoDB) CreateBillingIfNotExist() error {
-	if exist, err := m.collectionExist(m.AccountDB, m.BillingConn); exist || err != nil {
+func (m *mongoDB) CreateBillingIfNotExist(ctx context.Context) error {
+	if exist, err := m.collectionExist(ctx, m.AccountDB, m.BillingConn); exist || err != nil {
 		return err
 	}
-	ctx := context.Background()
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	err := m.Client.Database(m.AccountDB).CreateCollection(ctx, m.BillingConn)
 	if err != nil {
 		return fmt.Errorf("failed to create collection for billing: %w", err)
 	}
 
 	// create index
-	_, err = m.getBillingCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
-		{
-			// unique index owner order_id
-			Keys:    bson.D{primitive.E{Key: "owner", Value: 1}, primitive.E{Key: "order_id", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
-		{
-			// owner + time + type indexes
-			Keys: bson.D{
-				primitive.E{Key: "owner", Value: 1},
-				primitive.E{Key: "time", Value: 1},
-				primitive.E{Key: "type", Value: 1},
-			},
-		},
-	})
+	_, err = m.getBillingCollection().Indexes().CreateMany(ctx, billingIndexes)
 	if err != nil {
 		return fmt.Errorf("failed to create index for billing: %w", err)
 	}
@@ -946,38 +1042,52 @@ func (m *mongoDB) CreateBillingIfNotExist() error {
 }
 
 // CreateMonitorTimeSeriesIfNotExist creates the time series table for monitor
-func (m *mongoDB) CreateMonitorTimeSeriesIfNotExist(collTime time.Time) error {
-	return m.CreateTimeSeriesIfNotExist(m.AccountDB, m.getMonitorCollectionName(collTime))
+func (m *mongoDB) CreateMonitorTimeSeriesIfNotExist(ctx context.Context, collTime time.Time) error {
+	return m.CreateTimeSeriesIfNotExist(ctx, m.AccountDB, m.getMonitorCollectionName(collTime))
+}
+
+func (m *mongoDB) CreateTimeSeriesIfNotExist(ctx context.Context, dbName, collectionName string) error {
+	return m.createTimeSeriesIfNotExist(ctx, dbName, collectionName, "")
 }
 
-func (m *mongoDB) CreateTimeSeriesIfNotExist(dbName, collectionName string) error {
+// createTimeSeriesIfNotExist creates a time series collection with the given metaField, none if it is empty.
+// Secondary indexes on a time series collection without a metaField need MongoDB 6.0 or later.
+func (m *mongoDB) createTimeSeriesIfNotExist(ctx context.Context, dbName, collectionName, metaField string) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	// Check if the collection already exists
-	if exist, err := m.collectionExist(dbName, collectionName); exist || err != nil {
+	if exist, err := m.collectionExist(ctx, dbName, collectionName); exist || err != nil {
 		return err
 	}
 
 	// If the collection does not exist, create it
+	timeseries := bson.D{{Key: "timeField", Value: "time"}}
+	if metaField != "" {
+		timeseries = append(timeseries, primitive.E{Key: "metaField", Value: metaField})
+	}
 	cmd := bson.D{
 		primitive.E{Key: "create", Value: collectionName},
-		primitive.E{Key: "timeseries", Value: bson.D{{Key: "timeField", Value: "time"}}},
+		primitive.E{Key: "timeseries", Value: timeseries},
 	}
-	return m.Client.Database(dbName).RunCommand(context.TODO(), cmd).Err()
+	return m.Client.Database(dbName).RunCommand(ctx, cmd).Err()
 }
 
-func (m *mongoDB) DropMonitorCollectionsOlderThan(days int) error {
+func (m *mongoDB) DropMonitorCollectionsOlderThan(ctx context.Context, days int) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	db := m.Client.Database(m.AccountDB)
 	// Get the current time minus the number of days
 	cutoffDate := time.Now().UTC().AddDate(0, 0, -days)
 	cutoffName := m.getMonitorCollectionName(cutoffDate)
 
-	collections, err := db.ListCollectionNames(context.Background(), bson.M{})
+	collections, err := db.ListCollectionNames(ctx, bson.M{})
 	if err != nil {
 		return err
 	}
 	for i := range collections {
 		// Check if the collection name starts with the prefix and is older than the cutoff date
 		if strings.HasPrefix(collections[i], m.MonitorConnPrefix) && collections[i] < cutoffName {
-			if err := db.Collection(collections[i]).Drop(context.TODO()); err != nil {
+			if err := db.Collection(collections[i]).Drop(ctx); err != nil {
 				return err
 			}
 			logger.Info("dropped collection: ", collections[i])
@@ -986,14 +1096,20 @@ func (m *mongoDB) DropMonitorCollectionsOlderThan(days int) error {
 	return nil
 }
 
-func (m *mongoDB) collectionExist(dbName, collectionName string) (bool, error) {
+func (m *mongoDB) collectionExist(ctx context.Context, dbName, collectionName string) (bool, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	// Check if the collection already exists
-	collections, err := m.Client.Database(dbName).ListCollectionNames(context.Background(), bson.M{"name": collectionName})
+	collections, err := m.Client.Database(dbName).ListCollectionNames(ctx, bson.M{"name": collectionName})
 	return len(collections) > 0, err
 }
 
 func NewMongoInterface(ctx context.Context, URL string) (database.Interface, error) {
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(URL))
+	opts, err := newClientOptions(URL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -1008,9 +1124,19 @@ func NewMongoInterface(ctx context.Context, URL string) (database.Interface, err
 		MeteringConn:      DefaultMeteringConn,
 		MonitorConnPrefix: DefaultMonitorConn,
 		BillingConn:       DefaultBillingConn,
+		BillingWatchConn:  DefaultBillingWatch,
+		InvoiceConn:       DefaultInvoiceConn,
+		CounterConn:       DefaultCounterConn,
+		DevboxUsageConn:   DefaultDevboxUsage,
+		SpendingCapConn:   DefaultSpendingCap,
+		SuspendConn:       DefaultSuspendRecomm,
 		PricesConn:        DefaultPricesConn,
 		PropertiesConn:    DefaultPropertiesConn,
 		TrafficConn:       env.GetEnvWithDefault(EnvTrafficConn, DefaultTrafficConn),
+		TrafficArchive:    env.GetEnvWithDefault(EnvTrafficArchiveConn, DefaultTrafficArchiveConn),
+		NodePortTraffic:   env.GetEnvWithDefault(EnvNodePortTraffic, DefaultNodePortTraffic),
+		OperationTimeout:  env.GetDurationEnvWithDefault(EnvOperationTimeout, DefaultOperationTimeout),
+		IndexBuildTimeout: env.GetDurationEnvWithDefault(EnvIndexBuildTimeout, DefaultIndexBuildTimeout),
 		CvmConn:           env.GetEnvWithDefault(EnvCVMConn, DefaultCVMConn),
 	}, err
 }
@@ -107,7 +107,7 @@ func TestMongoDB_QueryBillingRecords(t *testing.T) {
 		query1, query2, query3, query4, query5,
 	}
 	for _, billingRecordQuery := range billingRecordQueryList {
-		err = m.QueryBillingRecords(billingRecordQuery, "vd1k1dk3")
+		err = m.QueryBillingRecords(dbCTX, billingRecordQuery, "vd1k1dk3")
 		if err != nil {
 			t.Errorf("failed to query billing records: error = %v", err)
 		}
@@ -140,7 +140,7 @@ func TestMongoDB_QueryBillingRecords1(t *testing.T) {
 			Type:      1,
 		},
 	}
-	err = m.QueryBillingRecords(billquery, "")
+	err = m.QueryBillingRecords(dbCTX, billquery, "")
 	if err != nil {
 		t.Errorf("failed to query billing records: error = %v", err)
 	}
@@ -228,7 +228,7 @@ func TestMongoDB_QueryBillingRecords2(t *testing.T) {
 		query1, /*query2, query3, query4, query5,*/
 	}
 	for _, billingRecordQuery := range billingRecordQueryList {
-		err = m.QueryBillingRecords(billingRecordQuery, "1jc12uh6")
+		err = m.QueryBillingRecords(dbCTX, billingRecordQuery, "1jc12uh6")
 		if err != nil {
 			t.Errorf("failed to query billing records: error = %v", err)
 		}
@@ -322,10 +322,10 @@ func TestMongoDB_SaveBillingsWithAccountBalance(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := m.CreateBillingIfNotExist(); err != nil {
+			if err := m.CreateBillingIfNotExist(dbCTX); err != nil {
 				t.Fatalf("failed to create billing time series: error = %v", err)
 			}
-			if err := m.SaveBillings(tt.args.accountBalanceSpec); (err != nil) != tt.wantErr {
+			if err := m.SaveBillings(dbCTX, tt.args.accountBalanceSpec); (err != nil) != tt.wantErr {
 				t.Fatalf("SaveBillingsWithAccountBalance() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -469,7 +469,7 @@ func TestMongoDB_GetBillingLastUpdateTime(t *testing.T) {
 		}
 	}()
 
-	exist, lastUpdateTime, err := m.GetBillingLastUpdateTime("vlemql0v", 0)
+	exist, lastUpdateTime, err := m.GetBillingLastUpdateTime(dbCTX, "vlemql0v", 0)
 	if err != nil {
 		t.Fatalf("failed to get billing last update time: error = %v", err)
 	}
@@ -491,7 +491,7 @@ func TestMongoDB_GetAllPricesMap(t *testing.T) {
 			t.Errorf("failed to disconnect mongo: error = %v", err)
 		}
 	}()
-	pricesMap, err := m.GetAllPricesMap()
+	pricesMap, err := m.GetAllPricesMap(dbCTX)
 	if err != nil {
 		t.Fatalf("failed to get all prices map: %v", err)
 	}
@@ -510,7 +510,7 @@ func TestMongoDB_DropMonitorCollectionsOlderThan(t *testing.T) {
 		}
 	}()
 	// 0711
-	if err = m.DropMonitorCollectionsOlderThan(30); err != nil {
+	if err = m.DropMonitorCollectionsOlderThan(dbCTX, 30); err != nil {
 		t.Fatalf("failed to drop monitor collections older than 30 days: %v", err)
 	}
 }
@@ -532,7 +532,7 @@ func TestMongoDB_GetBillingHistoryNamespaceList(t *testing.T) {
 		EndTime:   metav1.Time{Time: queryTime},
 		Type:      -1,
 	}
-	namespaceList, err := m.GetBillingHistoryNamespaceList(billRecord, "")
+	namespaceList, err := m.GetBillingHistoryNamespaceList(dbCTX, billRecord, "")
 	if err != nil {
 		t.Fatalf("failed to get billing history namespace list: %v", err)
 	}
@@ -560,7 +560,7 @@ func TestMongoDB_GenerateBillingData(t *testing.T) {
 	}()
 	queryTime := time.Now().UTC()
 
-	ids, amount, err := m.GenerateBillingData(queryTime.Add(-1*time.Hour), queryTime, resources.DefaultPropertyTypeLS, []string{"ns-7uyfrr47", "ns-1jc12uh6", "ns-ezplle8l"}, "1jc12uh6")
+	ids, amount, err := m.GenerateBillingData(dbCTX, queryTime.Add(-1*time.Hour), queryTime, resources.DefaultPropertyTypeLS, []string{"ns-7uyfrr47", "ns-1jc12uh6", "ns-ezplle8l"}, "1jc12uh6")
 	if err != nil {
 		t.Fatalf("failed to generate billing data: %v", err)
 	}
@@ -580,7 +580,7 @@ func TestMongoDB_SetPropertyTypeLS(t *testing.T) {
 			t.Errorf("failed to disconnect mongo: error = %v", err)
 		}
 	}()
-	err = m.InitDefaultPropertyTypeLS()
+	err = m.InitDefaultPropertyTypeLS(dbCTX)
 	if err != nil {
 		t.Fatalf("failed to get property type ls: %v", err)
 	}
@@ -608,7 +608,7 @@ func Test_mongoDB_GetDistinctMonitorCombinations(t *testing.T) {
 		}
 	}()
 	queryTime := time.Now().UTC()
-	monitorCombinations, err := m.GetDistinctMonitorCombinations(queryTime.Add(-time.Hour), queryTime)
+	monitorCombinations, err := m.GetDistinctMonitorCombinations(dbCTX, queryTime.Add(-time.Hour), queryTime)
 	if err != nil {
 		t.Fatalf("failed to get distinct monitor combinations: %v", err)
 	}
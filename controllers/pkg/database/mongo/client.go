@@ -0,0 +1,134 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/labring/sealos/controllers/pkg/utils/env"
+)
+
+const (
+	EnvMaxPoolSize            = "MONGO_MAX_POOL_SIZE"
+	EnvMinPoolSize            = "MONGO_MIN_POOL_SIZE"
+	EnvMaxConnIdleTime        = "MONGO_MAX_CONN_IDLE_TIME"
+	EnvConnectTimeout         = "MONGO_CONNECT_TIMEOUT"
+	EnvServerSelectionTimeout = "MONGO_SERVER_SELECTION_TIMEOUT"
+	// EnvOperationTimeout bounds every database operation in addition to the caller context
+	EnvOperationTimeout = "MONGO_OPERATION_TIMEOUT"
+	// EnvIndexBuildTimeout bounds the creation of the missing indexes of a collection, which takes long on large collections
+	EnvIndexBuildTimeout = "MONGO_INDEX_BUILD_TIMEOUT"
+)
+
+const (
+	DefaultMaxPoolSize            = 100
+	DefaultMinPoolSize            = 0
+	DefaultMaxConnIdleTime        = 5 * time.Minute
+	DefaultConnectTimeout         = 10 * time.Second
+	DefaultServerSelectionTimeout = 30 * time.Second
+	DefaultOperationTimeout       = time.Minute
+	DefaultIndexBuildTimeout      = time.Hour
+)
+
+var (
+	poolConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sealos_mongo_pool_connections",
+		Help: "Number of open connections in the mongo connection pool",
+	}, []string{"address"})
+	poolConnectionsInUse = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sealos_mongo_pool_connections_in_use",
+		Help: "Number of connections checked out of the mongo connection pool",
+	}, []string{"address"})
+	poolCheckoutFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sealos_mongo_pool_checkout_failures_total",
+		Help: "Number of failed connection checkouts from the mongo connection pool",
+	}, []string{"address", "reason"})
+	poolCleared = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sealos_mongo_pool_cleared_total",
+		Help: "Number of times the mongo connection pool was cleared, eg: on failover",
+	}, []string{"address"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(poolConnections, poolConnectionsInUse, poolCheckoutFailures, poolCleared)
+}
+
+func newPoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(evt *event.PoolEvent) {
+			switch evt.Type {
+			case event.ConnectionCreated:
+				poolConnections.WithLabelValues(evt.Address).Inc()
+			case event.ConnectionClosed:
+				poolConnections.WithLabelValues(evt.Address).Dec()
+			case event.GetSucceeded:
+				poolConnectionsInUse.WithLabelValues(evt.Address).Inc()
+			case event.ConnectionReturned:
+				poolConnectionsInUse.WithLabelValues(evt.Address).Dec()
+			case event.GetFailed:
+				poolCheckoutFailures.WithLabelValues(evt.Address, evt.Reason).Inc()
+			case event.PoolCleared:
+				poolCleared.WithLabelValues(evt.Address).Inc()
+			}
+		},
+	}
+}
+
+// newClientOptions builds the client options from the uri and the pool/timeout env settings,
+// retryable reads and writes are always enabled so that operations survive a replica set failover.
+func newClientOptions(URL string) (*options.ClientOptions, error) {
+	maxPoolSize := env.GetInt64EnvWithDefault(EnvMaxPoolSize, DefaultMaxPoolSize)
+	if maxPoolSize < 0 {
+		return nil, fmt.Errorf("env %s must not be negative: %d", EnvMaxPoolSize, maxPoolSize)
+	}
+	minPoolSize := env.GetInt64EnvWithDefault(EnvMinPoolSize, DefaultMinPoolSize)
+	if minPoolSize < 0 {
+		return nil, fmt.Errorf("env %s must not be negative: %d", EnvMinPoolSize, minPoolSize)
+	}
+	return options.Client().ApplyURI(URL).
+		SetMaxPoolSize(uint64(maxPoolSize)).
+		SetMinPoolSize(uint64(minPoolSize)).
+		SetMaxConnIdleTime(env.GetDurationEnvWithDefault(EnvMaxConnIdleTime, DefaultMaxConnIdleTime)).
+		SetConnectTimeout(env.GetDurationEnvWithDefault(EnvConnectTimeout, DefaultConnectTimeout)).
+		SetServerSelectionTimeout(env.GetDurationEnvWithDefault(EnvServerSelectionTimeout, DefaultServerSelectionTimeout)).
+		SetRetryWrites(true).
+		SetRetryReads(true).
+		SetPoolMonitor(newPoolMonitor()), nil
+}
+
+// operationContext bounds an operation by the caller context and the operation timeout.
+func (m *mongoDB) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := m.OperationTimeout
+	if timeout <= 0 {
+		timeout = DefaultOperationTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// indexBuildContext bounds an index build by the caller context and the index build timeout.
+func (m *mongoDB) indexBuildContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := m.IndexBuildTimeout
+	if timeout <= 0 {
+		timeout = DefaultIndexBuildTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
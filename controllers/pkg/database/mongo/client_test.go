@@ -0,0 +1,82 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+// newTestMongoDB connects to MONGODB_URI with account and traffic databases of its own, which are dropped
+// when the test ends. The test is skipped if MONGODB_URI is not set.
+func newTestMongoDB(t *testing.T) *mongoDB {
+	t.Helper()
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI is not set")
+	}
+	db, err := NewMongoInterface(context.Background(), uri)
+	if err != nil {
+		t.Fatalf("failed to connect mongo: error = %v", err)
+	}
+	m := db.(*mongoDB)
+	suffix := fmt.Sprintf("-test-%d", time.Now().UnixNano())
+	m.AccountDB += suffix
+	m.TrafficDB += suffix
+	t.Cleanup(func() {
+		ctx := context.Background()
+		for _, name := range []string{m.AccountDB, m.TrafficDB} {
+			if err := m.Client.Database(name).Drop(ctx); err != nil {
+				t.Errorf("failed to drop database %s: error = %v", name, err)
+			}
+		}
+		if err := m.Disconnect(ctx); err != nil {
+			t.Errorf("failed to disconnect mongo: error = %v", err)
+		}
+	})
+	return m
+}
+
+// testPropertyTypeLS copies the default property types with the unit prices of prices, keyed by property name.
+func testPropertyTypeLS(prices map[string]float64) *resources.PropertyTypeLS {
+	prols := &resources.PropertyTypeLS{StringMap: map[string]resources.PropertyType{}, EnumMap: map[uint8]resources.PropertyType{}}
+	for _, prop := range resources.DefaultPropertyTypeLS.Types {
+		if price, ok := prices[prop.Name]; ok {
+			prop.UnitPrice = price
+		}
+		prols.Types = append(prols.Types, prop)
+		prols.StringMap[prop.Name], prols.EnumMap[prop.Enum] = prop, prop
+	}
+	return prols
+}
+
+func TestNewClientOptions_NegativePoolSize(t *testing.T) {
+	for _, key := range []string{EnvMaxPoolSize, EnvMinPoolSize} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, "-1")
+			if _, err := newClientOptions("mongodb://localhost:27017"); err == nil {
+				t.Errorf("newClientOptions() with %s=-1 error = nil", key)
+			}
+		})
+	}
+	if _, err := newClientOptions("mongodb://localhost:27017"); err != nil {
+		t.Errorf("newClientOptions() error = %v", err)
+	}
+}
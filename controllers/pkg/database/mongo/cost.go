@@ -0,0 +1,197 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+// GetCostBreakdown aggregates the consumption billing of owner in [startTime, endTime) server side,
+// grouped by app type, app name (with its app type) or resource property. An empty namespace means all namespaces.
+func (m *mongoDB) GetCostBreakdown(ctx context.Context, owner, namespace string, startTime, endTime time.Time, prols *resources.PropertyTypeLS, groupBy database.CostGroupBy) ([]database.CostBreakdownItem, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("owner is empty")
+	}
+	match := bson.M{
+		"owner": owner,
+		"type":  accountv1.Consumption,
+		"time": bson.M{
+			"$gte": startTime.UTC(),
+			"$lt":  endTime.UTC(),
+		},
+	}
+	if namespace != "" {
+		match["namespace"] = namespace
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+	}
+	switch groupBy {
+	case database.CostGroupByAppType:
+		pipeline = append(pipeline,
+			bson.D{{Key: "$group", Value: bson.M{
+				"_id":    bson.M{"app_type": "$app_type"},
+				"amount": bson.M{"$sum": "$amount"},
+			}}},
+		)
+	case database.CostGroupByAppName:
+		pipeline = append(pipeline,
+			bson.D{{Key: "$unwind", Value: "$app_costs"}},
+			bson.D{{Key: "$group", Value: bson.M{
+				"_id":    bson.M{"app_type": "$app_type", "app_name": "$app_costs.name"},
+				"amount": bson.M{"$sum": "$app_costs.amount"},
+			}}},
+		)
+	case database.CostGroupByResource:
+		pipeline = append(pipeline,
+			bson.D{{Key: "$unwind", Value: "$app_costs"}},
+			bson.D{{Key: "$project", Value: bson.M{
+				"used_amount": bson.M{"$objectToArray": "$app_costs.used_amount"},
+			}}},
+			bson.D{{Key: "$unwind", Value: "$used_amount"}},
+			bson.D{{Key: "$group", Value: bson.M{
+				"_id":    bson.M{"resource": "$used_amount.k"},
+				"amount": bson.M{"$sum": "$used_amount.v"},
+			}}},
+		)
+	default:
+		return nil, fmt.Errorf("unsupported group by: %s", groupBy)
+	}
+
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	cursor, err := m.getBillingCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute aggregate query: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var items []database.CostBreakdownItem
+	for cursor.Next(ctx) {
+		var result struct {
+			ID struct {
+				AppType  uint8  `bson:"app_type"`
+				AppName  string `bson:"app_name"`
+				Resource string `bson:"resource"`
+			} `bson:"_id"`
+			Amount int64 `bson:"amount"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode cost breakdown: %w", err)
+		}
+		item := database.CostBreakdownItem{Amount: result.Amount}
+		switch groupBy {
+		case database.CostGroupByAppType:
+			item.AppType = resources.AppTypeReverse[result.ID.AppType]
+		case database.CostGroupByAppName:
+			item.AppType, item.AppName = resources.AppTypeReverse[result.ID.AppType], result.ID.AppName
+		case database.CostGroupByResource:
+			// used_amount keys are the property enums
+			enum, err := strconv.Atoi(result.ID.Resource)
+			if err != nil {
+				return nil, fmt.Errorf("invalid property enum %s: %w", result.ID.Resource, err)
+			}
+			item.Resource = propertyName(prols, uint8(enum))
+		}
+		items = append(items, item)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Amount > items[j].Amount
+	})
+	return items, nil
+}
+
+// propertyName returns the name of the property enum in prols, or the enum itself if prols does not know it.
+func propertyName(prols *resources.PropertyTypeLS, enum uint8) string {
+	if prols != nil {
+		if property, ok := prols.EnumMap[enum]; ok {
+			return property.Name
+		}
+	}
+	return strconv.Itoa(int(enum))
+}
+
+func (m *mongoDB) GetBillingAmountSeries(ctx context.Context, startTime, endTime time.Time) ([]database.BillingAmountSample, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"type": accountv1.Consumption,
+			"time": bson.M{
+				"$gte": startTime.UTC(),
+				"$lt":  endTime.UTC(),
+			},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"namespace": "$namespace",
+				"app_type":  "$app_type",
+				// cvm billing is not aligned to the hour, $dateFromParts rather than $dateTrunc which needs MongoDB 5.0
+				"time": bson.M{"$dateFromParts": bson.M{
+					"year":  bson.M{"$year": "$time"},
+					"month": bson.M{"$month": "$time"},
+					"day":   bson.M{"$dayOfMonth": "$time"},
+					"hour":  bson.M{"$hour": "$time"},
+				}},
+			},
+			"amount": bson.M{"$sum": "$amount"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.time", Value: 1}, {Key: "_id.namespace", Value: 1}, {Key: "_id.app_type", Value: 1}}}},
+	}
+	cursor, err := m.getBillingCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute aggregate query: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var samples []database.BillingAmountSample
+	for cursor.Next(ctx) {
+		var result struct {
+			ID struct {
+				Namespace string    `bson:"namespace"`
+				AppType   uint8     `bson:"app_type"`
+				Time      time.Time `bson:"time"`
+			} `bson:"_id"`
+			Amount int64 `bson:"amount"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode billing amount: %w", err)
+		}
+		samples = append(samples, database.BillingAmountSample{
+			Namespace: result.ID.Namespace,
+			AppType:   resources.AppTypeReverse[result.ID.AppType],
+			Time:      result.ID.Time.UTC(),
+			Amount:    result.Amount,
+		})
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return samples, nil
+}
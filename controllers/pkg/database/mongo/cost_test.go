@@ -0,0 +1,88 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+func TestMongoDB_GetCostBreakdown(t *testing.T) {
+	m := newTestMongoDB(t)
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prols := testPropertyTypeLS(nil)
+	cpu := prols.StringMap["cpu"].Enum
+	// a property only configured in the database
+	custom := resources.PropertyType{Name: "custom", Enum: 200, PriceType: resources.SUM}
+	prols.Types = append(prols.Types, custom)
+	prols.StringMap[custom.Name], prols.EnumMap[custom.Enum] = custom, custom
+	if err := m.SaveBillings(context.Background(),
+		&resources.Billing{OrderID: "order1", Owner: "owner1", Type: accountv1.Consumption, Namespace: "ns-a", AppType: resources.AppType[resources.APP], Amount: 30, Time: startTime.Add(time.Hour),
+			AppCosts: []resources.AppCost{{Name: "app1", Amount: 30, UsedAmount: resources.EnumUsedMap{cpu: 10, custom.Enum: 20}}}},
+		&resources.Billing{OrderID: "order2", Owner: "owner1", Type: accountv1.Consumption, Namespace: "ns-b", AppType: resources.AppType[resources.DB], Amount: 5, Time: startTime.Add(2 * time.Hour),
+			AppCosts: []resources.AppCost{{Name: "db1", Amount: 5, UsedAmount: resources.EnumUsedMap{cpu: 5}}}},
+		&resources.Billing{OrderID: "order3", Owner: "owner2", Type: accountv1.Consumption, Namespace: "ns-c", AppType: resources.AppType[resources.APP], Amount: 100, Time: startTime.Add(time.Hour),
+			AppCosts: []resources.AppCost{{Name: "app2", Amount: 100, UsedAmount: resources.EnumUsedMap{cpu: 100}}}},
+	); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+
+	tests := map[database.CostGroupBy][]database.CostBreakdownItem{
+		database.CostGroupByAppType:  {{AppType: resources.APP, Amount: 30}, {AppType: resources.DB, Amount: 5}},
+		database.CostGroupByAppName:  {{AppType: resources.APP, AppName: "app1", Amount: 30}, {AppType: resources.DB, AppName: "db1", Amount: 5}},
+		database.CostGroupByResource: {{Resource: "custom", Amount: 20}, {Resource: "cpu", Amount: 15}},
+	}
+	for groupBy, want := range tests {
+		items, err := m.GetCostBreakdown(context.Background(), "owner1", "", startTime, startTime.Add(3*time.Hour), prols, groupBy)
+		if err != nil {
+			t.Fatalf("GetCostBreakdown(%s) error = %v", groupBy, err)
+		}
+		if !reflect.DeepEqual(items, want) {
+			t.Errorf("GetCostBreakdown(%s) = %+v, want %+v", groupBy, items, want)
+		}
+	}
+}
+
+func TestMongoDB_GetBillingAmountSeries(t *testing.T) {
+	m := newTestMongoDB(t)
+	hour := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	if err := m.SaveBillings(context.Background(),
+		&resources.Billing{OrderID: "order1", Owner: "owner1", Type: accountv1.Consumption, Namespace: "ns-a", AppType: resources.AppType[resources.APP], Amount: 1500000, Time: hour},
+		&resources.Billing{OrderID: "order2", Owner: "owner2", Type: accountv1.Consumption, Namespace: "ns-a", AppType: resources.AppType[resources.APP], Amount: 500000, Time: hour},
+		// cvm billing is not aligned to the hour
+		&resources.Billing{OrderID: "order3", Owner: "owner1", Type: accountv1.Consumption, Namespace: "ns-b", AppType: resources.AppType[resources.CVM], Amount: 25, Time: hour.Add(10 * time.Minute)},
+		&resources.Billing{OrderID: "order4", Owner: "owner1", Type: accountv1.Recharge, Namespace: "ns-a", Amount: 100, Time: hour},
+		&resources.Billing{OrderID: "order5", Owner: "owner1", Type: accountv1.Consumption, Namespace: "ns-a", AppType: resources.AppType[resources.APP], Amount: 1, Time: hour.Add(time.Hour)},
+	); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+	samples, err := m.GetBillingAmountSeries(context.Background(), hour, hour.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetBillingAmountSeries() error = %v", err)
+	}
+	want := []database.BillingAmountSample{
+		{Namespace: "ns-a", AppType: resources.APP, Time: hour, Amount: 2000000},
+		{Namespace: "ns-b", AppType: resources.CVM, Time: hour, Amount: 25},
+	}
+	if !reflect.DeepEqual(samples, want) {
+		t.Errorf("GetBillingAmountSeries() = %+v, want %+v", samples, want)
+	}
+}
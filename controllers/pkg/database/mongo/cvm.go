@@ -26,7 +26,9 @@ import (
 	"github.com/labring/sealos/controllers/pkg/types"
 )
 
-func (m *mongoDB) GetPendingStateInstance(regionUID string) (cvmMap map[string][]types.CVMBilling, err error) {
+func (m *mongoDB) GetPendingStateInstance(ctx context.Context, regionUID string) (cvmMap map[string][]types.CVMBilling, err error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	if regionUID == "" {
 		return nil, fmt.Errorf("region UID is empty")
 	}
@@ -38,14 +40,14 @@ func (m *mongoDB) GetPendingStateInstance(regionUID string) (cvmMap map[string][
 			"$eq": regionUID,
 		},
 	}
-	cur, err := m.getCVMCollection().Find(context.Background(), filter)
+	cur, err := m.getCVMCollection().Find(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find with filter: %v", err)
 	}
-	defer cur.Close(context.Background())
+	defer cur.Close(ctx)
 	cvm := make([]types.CVMBilling, 0)
 	cvmMap = make(map[string][]types.CVMBilling)
-	err = cur.All(context.Background(), &cvm)
+	err = cur.All(ctx, &cvm)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +61,9 @@ func (m *mongoDB) GetPendingStateInstance(regionUID string) (cvmMap map[string][
 	return cvmMap, nil
 }
 
-func (m *mongoDB) SetDoneStateInstance(instanceIDs ...primitive.ObjectID) error {
+func (m *mongoDB) SetDoneStateInstance(ctx context.Context, instanceIDs ...primitive.ObjectID) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	if len(instanceIDs) == 0 {
 		return fmt.Errorf("instanceIDs is empty")
 	}
@@ -73,7 +77,7 @@ func (m *mongoDB) SetDoneStateInstance(instanceIDs ...primitive.ObjectID) error
 			"state": types.CVMBillingStateDone,
 		},
 	}
-	_, err := m.getCVMCollection().UpdateMany(context.Background(), filter, update)
+	_, err := m.getCVMCollection().UpdateMany(ctx, filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to update with filter: %v", err)
 	}
@@ -0,0 +1,90 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+func (m *mongoDB) SaveDevboxUsage(ctx context.Context, usages ...*resources.DevboxUsage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	docs := make([]interface{}, len(usages))
+	for i := range usages {
+		usages[i].Time = usages[i].Time.UTC()
+		docs[i] = usages[i]
+	}
+	if _, err := m.getDevboxUsageCollection().InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to save devbox usage: %w", err)
+	}
+	return nil
+}
+
+func (m *mongoDB) AggregateDevboxUsage(ctx context.Context, owner string, startTime, endTime time.Time) ([]resources.DevboxUsageSummary, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"owner": owner,
+			"time": bson.M{
+				"$gte": startTime.UTC(),
+				"$lt":  endTime.UTC(),
+			},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":               bson.M{"namespace": "$namespace", "name": "$name"},
+			"commit_count":      bson.M{"$sum": "$commit_count"},
+			"avg_storage_bytes": bson.M{"$avg": "$storage_bytes"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":               0,
+			"namespace":         "$_id.namespace",
+			"name":              "$_id.name",
+			"commit_count":      1,
+			"avg_storage_bytes": 1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "namespace", Value: 1}, {Key: "name", Value: 1}}}},
+	}
+	cursor, err := m.getDevboxUsageCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate devbox usage: %w", err)
+	}
+	defer cursor.Close(ctx)
+	var summaries []resources.DevboxUsageSummary
+	if err := cursor.All(ctx, &summaries); err != nil {
+		return nil, fmt.Errorf("failed to decode devbox usage: %w", err)
+	}
+	return summaries, nil
+}
+
+// CreateDevboxUsageIfNotExist creates the devbox usage time series collection. The owner is the metaField so
+// that the owner index is supported on MongoDB 5.0, a collection created before without it needs MongoDB 6.0.
+func (m *mongoDB) CreateDevboxUsageIfNotExist(ctx context.Context) error {
+	return m.createTimeSeriesIfNotExist(ctx, m.AccountDB, m.DevboxUsageConn, "owner")
+}
+
+func (m *mongoDB) getDevboxUsageCollection() *mongo.Collection {
+	return m.Client.Database(m.AccountDB).Collection(m.DevboxUsageConn)
+}
@@ -0,0 +1,60 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+func TestMongoDB_AggregateDevboxUsage(t *testing.T) {
+	m := newTestMongoDB(t)
+	if err := m.CreateDevboxUsageIfNotExist(context.Background()); err != nil {
+		t.Fatalf("failed to create devbox usage: %v", err)
+	}
+	if err := m.CreateBillingIfNotExist(context.Background()); err != nil {
+		t.Fatalf("failed to create billing: %v", err)
+	}
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(time.Hour)
+	prols := testPropertyTypeLS(map[string]float64{resources.DevboxStorage: 512, resources.DevboxCommit: 100})
+	if err := m.SaveDevboxUsage(context.Background(),
+		&resources.DevboxUsage{Time: startTime, Owner: "owner1", Namespace: "ns-test", Name: "devbox1", CommitCount: 1, StorageBytes: 1 << 30},
+		&resources.DevboxUsage{Time: startTime.Add(30 * time.Minute), Owner: "owner1", Namespace: "ns-test", Name: "devbox1", CommitCount: 2, StorageBytes: 3 << 30},
+		&resources.DevboxUsage{Time: startTime.Add(30 * time.Minute), Owner: "owner1", Namespace: "ns-other", Name: "devbox2", CommitCount: 1, StorageBytes: 1 << 30},
+	); err != nil {
+		t.Fatalf("failed to save devbox usage: %v", err)
+	}
+
+	summaries, err := m.AggregateDevboxUsage(context.Background(), "owner1", startTime, endTime)
+	if err != nil {
+		t.Fatalf("failed to aggregate devbox usage: %v", err)
+	}
+	if len(summaries) != 2 || summaries[1].Name != "devbox1" || summaries[1].CommitCount != 3 || summaries[1].AvgStorageBytes != 2<<30 {
+		t.Fatalf("AggregateDevboxUsage() = %+v", summaries)
+	}
+
+	// the devbox usage is storage only and not billed
+	ids, amount, err := m.GenerateBillingData(context.Background(), startTime, endTime, prols, []string{"ns-test"}, "owner1")
+	if err != nil {
+		t.Fatalf("failed to generate billing data: %v", err)
+	}
+	if amount != 0 || len(ids) != 0 {
+		t.Errorf("GenerateBillingData() = %v, %d, want no billing", ids, amount)
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/labring/sealos/controllers/pkg/utils/logger"
+)
+
+var (
+	missingIndexes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sealos_mongo_missing_indexes",
+		Help: "Number of declared indexes that were missing on the collection and could not be created",
+	}, []string{"database", "collection"})
+	mismatchedIndexes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sealos_mongo_mismatched_indexes",
+		Help: "Number of declared indexes that exist on the collection with different options",
+	}, []string{"database", "collection"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(missingIndexes, mismatchedIndexes)
+}
+
+var billingIndexes = []mongo.IndexModel{
+	{
+		// unique index owner order_id
+		Keys:    bson.D{primitive.E{Key: "owner", Value: 1}, primitive.E{Key: "order_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	{
+		// owner + time + type indexes: QueryBillingRecords, GetCostBreakdown, GetBillingUsage
+		Keys: bson.D{
+			primitive.E{Key: "owner", Value: 1},
+			primitive.E{Key: "time", Value: 1},
+			primitive.E{Key: "type", Value: 1},
+		},
+	},
+	{
+		// GetBillingLastUpdateTime: latest billing of owner and type
+		Keys: bson.D{
+			primitive.E{Key: "owner", Value: 1},
+			primitive.E{Key: "type", Value: 1},
+			primitive.E{Key: "time", Value: -1},
+		},
+	},
+	{
+		// GetUnsettingBillingHandler
+		Keys: bson.D{primitive.E{Key: "owner", Value: 1}, primitive.E{Key: "status", Value: 1}},
+	},
+	{
+		// UpdateBillingStatus
+		Keys: bson.D{primitive.E{Key: "order_id", Value: 1}},
+	},
+	{
+		// GetBillingCount, GetAllPayment
+		Keys: bson.D{primitive.E{Key: "type", Value: 1}, primitive.E{Key: "time", Value: 1}},
+	},
+}
+
+var invoiceIndexes = []mongo.IndexModel{
+	{
+		// one invoice per owner and period
+		Keys:    bson.D{primitive.E{Key: "owner", Value: 1}, primitive.E{Key: "period_start", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+}
+
+var propertiesIndexes = []mongo.IndexModel{
+	{
+		// one property type per name
+		Keys:    bson.D{primitive.E{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+}
+
+var meteringIndexes = []mongo.IndexModel{
+	{
+		// GetUpdateTimeForCategoryAndPropertyFromMetering: latest metering of category and property
+		Keys: bson.D{
+			primitive.E{Key: "category", Value: 1},
+			primitive.E{Key: "property", Value: 1},
+			primitive.E{Key: "time", Value: -1},
+		},
+	},
+}
+
+var devboxUsageIndexes = []mongo.IndexModel{
+	{
+		// AggregateDevboxUsage
+		Keys: bson.D{primitive.E{Key: "owner", Value: 1}, primitive.E{Key: "time", Value: 1}},
+	},
+}
+
+var spendingCapIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{primitive.E{Key: "owner", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+}
+
+var suspendRecommendationIndexes = []mongo.IndexModel{
+	{
+		// one recommendation per owner and period
+		Keys:    bson.D{primitive.E{Key: "owner", Value: 1}, primitive.E{Key: "period_start", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	{
+		// GetDueSuspendRecommendations
+		Keys: bson.D{primitive.E{Key: "suspend_at", Value: 1}},
+	},
+}
+
+var nodePortTrafficIndexes = []mongo.IndexModel{
+	{
+		// GetNodePortTraffic
+		Keys: bson.D{primitive.E{Key: "namespace", Value: 1}, primitive.E{Key: "time", Value: 1}},
+	},
+}
+
+var trafficIndexes = []mongo.IndexModel{
+	{
+		// getTrafficBytes: namespace + type + type name + timestamp
+		Keys: bson.D{
+			primitive.E{Key: "traffic_meta.pod_namespace", Value: 1},
+			primitive.E{Key: "traffic_meta.pod_type", Value: 1},
+			primitive.E{Key: "traffic_meta.pod_type_name", Value: 1},
+			primitive.E{Key: "timestamp", Value: 1},
+		},
+	},
+	{
+		// getPodTrafficBytes: namespace + pod name + timestamp
+		Keys: bson.D{
+			primitive.E{Key: "traffic_meta.pod_namespace", Value: 1},
+			primitive.E{Key: "traffic_meta.pod_name", Value: 1},
+			primitive.E{Key: "timestamp", Value: 1},
+		},
+	},
+}
+
+// EnsureIndexes creates the indexes the query methods rely on for every existing collection,
+// collections that do not exist yet are left to their Create*IfNotExist method.
+// Indexes that exist with the same keys but different options are only reported, as fixing them needs a rebuild.
+// The missing indexes of a collection are built under the index build timeout instead of the operation timeout.
+func (m *mongoDB) EnsureIndexes(ctx context.Context) error {
+	collections := []struct {
+		db, coll string
+		indexes  []mongo.IndexModel
+	}{
+		{m.AccountDB, m.BillingConn, billingIndexes},
+		{m.AccountDB, m.InvoiceConn, invoiceIndexes},
+		{m.AccountDB, m.PropertiesConn, propertiesIndexes},
+		{m.AccountDB, m.MeteringConn, meteringIndexes},
+		{m.AccountDB, m.DevboxUsageConn, devboxUsageIndexes},
+		{m.AccountDB, m.SpendingCapConn, spendingCapIndexes},
+		{m.AccountDB, m.SuspendConn, suspendRecommendationIndexes},
+		{m.TrafficDB, m.TrafficConn, trafficIndexes},
+		{m.TrafficDB, m.NodePortTraffic, nodePortTrafficIndexes},
+	}
+	var errs []string
+	for _, c := range collections {
+		if err := m.ensureCollectionIndexes(ctx, c.db, c.coll, c.indexes); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to ensure indexes: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (m *mongoDB) ensureCollectionIndexes(ctx context.Context, dbName, collName string, indexes []mongo.IndexModel) error {
+	if exist, err := m.collectionExist(ctx, dbName, collName); !exist || err != nil {
+		return err
+	}
+	coll := m.Client.Database(dbName).Collection(collName)
+	listCtx, cancel := m.operationContext(ctx)
+	defer cancel()
+	cur, err := coll.Indexes().List(listCtx)
+	if err != nil {
+		return fmt.Errorf("failed to list indexes of %s.%s: %w", dbName, collName, err)
+	}
+	var existing []struct {
+		Name   string `bson:"name"`
+		Key    bson.D `bson:"key"`
+		Unique bool   `bson:"unique"`
+	}
+	if err := cur.All(listCtx, &existing); err != nil {
+		return fmt.Errorf("failed to decode indexes of %s.%s: %w", dbName, collName, err)
+	}
+	unique := make(map[string]bool, len(existing))
+	for _, index := range existing {
+		unique[indexKeyString(index.Key)] = index.Unique
+	}
+
+	var missing []mongo.IndexModel
+	mismatched := 0
+	for _, index := range indexes {
+		key := indexKeyString(index.Keys.(bson.D))
+		wantUnique := index.Options != nil && index.Options.Unique != nil && *index.Options.Unique
+		gotUnique, ok := unique[key]
+		switch {
+		case !ok:
+			logger.Info("missing index %s on %s.%s, creating it", key, dbName, collName)
+			missing = append(missing, index)
+		case gotUnique != wantUnique:
+			logger.Warn("index %s on %s.%s has unique=%t, expected unique=%t", key, dbName, collName, gotUnique, wantUnique)
+			mismatched++
+		}
+	}
+	mismatchedIndexes.WithLabelValues(dbName, collName).Set(float64(mismatched))
+	missingIndexes.WithLabelValues(dbName, collName).Set(0)
+	if len(missing) == 0 {
+		return nil
+	}
+	// building an index on a large collection takes much longer than an operation
+	buildCtx, cancelBuild := m.indexBuildContext(ctx)
+	defer cancelBuild()
+	if _, err := coll.Indexes().CreateMany(buildCtx, missing); err != nil {
+		missingIndexes.WithLabelValues(dbName, collName).Set(float64(len(missing)))
+		return fmt.Errorf("failed to create indexes for %s.%s: %w", dbName, collName, err)
+	}
+	return nil
+}
+
+// indexKeyString formats the keys the same way as the default index name, eg: owner_1_time_-1
+func indexKeyString(keys bson.D) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
+}
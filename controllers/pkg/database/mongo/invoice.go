@@ -0,0 +1,180 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+const invoiceSequenceID = "invoice"
+
+func (m *mongoDB) GenerateInvoice(ctx context.Context, owner string, period time.Time) (*resources.Invoice, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("owner is empty")
+	}
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	start, end := resources.InvoicePeriod(period)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"owner": owner,
+			"type":  accountv1.Consumption,
+			"time": bson.M{
+				"$gte": start,
+				"$lt":  end,
+			},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"namespace": "$namespace", "app_type": "$app_type"},
+			"count":  bson.M{"$sum": 1},
+			"amount": bson.M{"$sum": "$amount"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.namespace", Value: 1}, {Key: "_id.app_type", Value: 1}}}},
+	}
+	cursor, err := m.getBillingCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute aggregate query: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	invoice := &resources.Invoice{
+		Owner:       owner,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Items:       []resources.InvoiceItem{},
+	}
+	for cursor.Next(ctx) {
+		var result struct {
+			ID struct {
+				Namespace string `bson:"namespace"`
+				AppType   uint8  `bson:"app_type"`
+			} `bson:"_id"`
+			Count  int64 `bson:"count"`
+			Amount int64 `bson:"amount"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode invoice item: %w", err)
+		}
+		invoice.Items = append(invoice.Items, resources.InvoiceItem{
+			Namespace: result.ID.Namespace,
+			AppType:   resources.AppTypeReverse[result.ID.AppType],
+			Count:     result.Count,
+			Amount:    result.Amount,
+		})
+		invoice.Amount += result.Amount
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	now := time.Now().UTC()
+	filter := bson.M{"owner": owner, "period_start": start}
+	for attempt := 0; ; attempt++ {
+		existing, err := m.GetInvoice(ctx, owner, start)
+		if err != nil {
+			return nil, err
+		}
+		update := bson.M{
+			"$set": bson.M{
+				"period_end": end,
+				"items":      invoice.Items,
+				"amount":     invoice.Amount,
+				"updated_at": now,
+			},
+		}
+		if existing == nil {
+			// the sequence is only allocated for a new invoice, a concurrent generation winning the insert leaves a gap
+			seq, err := m.nextSequence(ctx, invoiceSequenceID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get invoice sequence: %w", err)
+			}
+			update["$setOnInsert"] = bson.M{
+				"sequence":   seq,
+				"number":     resources.InvoiceNumber(start, seq),
+				"created_at": now,
+			}
+		}
+		opts := options.FindOneAndUpdate().SetUpsert(existing == nil).SetReturnDocument(options.After)
+		var saved resources.Invoice
+		err = m.getInvoiceCollection().FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved)
+		if err == nil {
+			return &saved, nil
+		}
+		// lost the insert race, or the invoice was removed in between
+		if attempt == 0 && (mongo.IsDuplicateKeyError(err) || err == mongo.ErrNoDocuments) {
+			continue
+		}
+		return nil, fmt.Errorf("failed to save invoice: %w", err)
+	}
+}
+
+// GetInvoice returns the invoice of owner for the month of period, nil if it has not been generated.
+func (m *mongoDB) GetInvoice(ctx context.Context, owner string, period time.Time) (*resources.Invoice, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	start, _ := resources.InvoicePeriod(period)
+	var invoice resources.Invoice
+	err := m.getInvoiceCollection().FindOne(ctx, bson.M{"owner": owner, "period_start": start}).Decode(&invoice)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	return &invoice, nil
+}
+
+func (m *mongoDB) nextSequence(ctx context.Context, id string) (int64, error) {
+	var result struct {
+		Seq int64 `bson:"seq"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := m.getCounterCollection().FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"seq": int64(1)}}, opts).Decode(&result)
+	return result.Seq, err
+}
+
+func (m *mongoDB) CreateInvoiceIfNotExist(ctx context.Context) error {
+	if exist, err := m.collectionExist(ctx, m.AccountDB, m.InvoiceConn); exist || err != nil {
+		return err
+	}
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	if err := m.Client.Database(m.AccountDB).CreateCollection(ctx, m.InvoiceConn); err != nil {
+		return fmt.Errorf("failed to create collection for invoice: %w", err)
+	}
+	_, err := m.getInvoiceCollection().Indexes().CreateMany(ctx, invoiceIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create index for invoice: %w", err)
+	}
+	return nil
+}
+
+func (m *mongoDB) getInvoiceCollection() *mongo.Collection {
+	return m.Client.Database(m.AccountDB).Collection(m.InvoiceConn)
+}
+
+func (m *mongoDB) getCounterCollection() *mongo.Collection {
+	return m.Client.Database(m.AccountDB).Collection(m.CounterConn)
+}
@@ -0,0 +1,86 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+func TestMongoDB_GenerateInvoice(t *testing.T) {
+	m := newTestMongoDB(t)
+	if err := m.CreateInvoiceIfNotExist(context.Background()); err != nil {
+		t.Fatalf("failed to create invoice: %v", err)
+	}
+	period := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	if err := m.SaveBillings(context.Background(),
+		&resources.Billing{OrderID: "order1", Owner: "owner1", Type: accountv1.Consumption, Namespace: "ns-a", AppType: resources.AppType[resources.APP], Amount: 100, Time: period},
+		&resources.Billing{OrderID: "order2", Owner: "owner1", Type: accountv1.Consumption, Namespace: "ns-a", AppType: resources.AppType[resources.APP], Amount: 200, Time: period},
+		&resources.Billing{OrderID: "order3", Owner: "owner1", Type: accountv1.Consumption, Namespace: "ns-a", AppType: resources.AppType[resources.APP], Amount: 300, Time: period.AddDate(0, 1, 0)},
+	); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+	invoice, err := m.GenerateInvoice(context.Background(), "owner1", period)
+	if err != nil {
+		t.Fatalf("failed to generate invoice: %v", err)
+	}
+	if invoice.Number != "INV-202401-000001" || invoice.Amount != 300 || len(invoice.Items) != 1 || invoice.Items[0].Count != 2 {
+		t.Fatalf("GenerateInvoice() = %+v", invoice)
+	}
+
+	if err := m.SaveBillings(context.Background(), &resources.Billing{OrderID: "order4", Owner: "owner1", Type: accountv1.Consumption, Namespace: "ns-b", AppType: resources.AppType[resources.DB], Amount: 50, Time: period}); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+	regenerated, err := m.GenerateInvoice(context.Background(), "owner1", period)
+	if err != nil {
+		t.Fatalf("failed to regenerate invoice: %v", err)
+	}
+	if regenerated.Number != invoice.Number || regenerated.Amount != 350 || len(regenerated.Items) != 2 {
+		t.Errorf("GenerateInvoice() regenerated = %+v", regenerated)
+	}
+
+	// concurrent generations of a new invoice agree on its number
+	next := period.AddDate(0, 1, 0)
+	numbers := make(chan string, 4)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(numbers); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			invoice, err := m.GenerateInvoice(context.Background(), "owner1", next)
+			if err != nil {
+				t.Errorf("failed to generate invoice concurrently: %v", err)
+				return
+			}
+			numbers <- invoice.Number
+		}()
+	}
+	wg.Wait()
+	close(numbers)
+	stored, err := m.GetInvoice(context.Background(), "owner1", next)
+	if err != nil || stored == nil {
+		t.Fatalf("GetInvoice() = %+v, %v", stored, err)
+	}
+	for number := range numbers {
+		if number != stored.Number {
+			t.Errorf("GenerateInvoice() concurrently = %s, stored %s", number, stored.Number)
+		}
+	}
+}
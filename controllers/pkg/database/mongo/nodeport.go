@@ -0,0 +1,93 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+func (m *mongoDB) InsertNodePortTraffic(ctx context.Context, records ...*resources.NodePortTraffic) error {
+	if len(records) == 0 {
+		return nil
+	}
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	docs := make([]interface{}, len(records))
+	for i := range records {
+		record := *records[i]
+		record.Time = record.Time.UTC()
+		docs[i] = record
+	}
+	if _, err := m.getNodePortTrafficCollection().InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to insert nodeport traffic: %w", err)
+	}
+	return nil
+}
+
+func (m *mongoDB) GetNodePortTraffic(ctx context.Context, namespace string, startTime, endTime time.Time) ([]resources.NodePortTrafficSummary, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	match := bson.M{
+		"time": bson.M{
+			"$gte": startTime.UTC(),
+			"$lt":  endTime.UTC(),
+		},
+	}
+	if namespace != "" {
+		match["namespace"] = namespace
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           bson.M{"namespace": "$namespace", "name": "$name"},
+			"ingress_bytes": bson.M{"$sum": "$ingress_bytes"},
+			"egress_bytes":  bson.M{"$sum": "$egress_bytes"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":           0,
+			"namespace":     "$_id.namespace",
+			"name":          "$_id.name",
+			"ingress_bytes": 1,
+			"egress_bytes":  1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "namespace", Value: 1}, {Key: "name", Value: 1}}}},
+	}
+	cursor, err := m.getNodePortTrafficCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate nodeport traffic: %w", err)
+	}
+	defer cursor.Close(ctx)
+	var summaries []resources.NodePortTrafficSummary
+	if err := cursor.All(ctx, &summaries); err != nil {
+		return nil, fmt.Errorf("failed to decode nodeport traffic: %w", err)
+	}
+	return summaries, nil
+}
+
+// CreateNodePortTrafficIfNotExist creates the nodeport traffic time series collection in the traffic database.
+func (m *mongoDB) CreateNodePortTrafficIfNotExist(ctx context.Context) error {
+	return m.CreateTimeSeriesIfNotExist(ctx, m.TrafficDB, m.NodePortTraffic)
+}
+
+func (m *mongoDB) getNodePortTrafficCollection() *mongo.Collection {
+	return m.Client.Database(m.TrafficDB).Collection(m.NodePortTraffic)
+}
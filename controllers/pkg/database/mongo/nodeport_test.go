@@ -0,0 +1,59 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+func TestMongoDB_GetNodePortTraffic(t *testing.T) {
+	m := newTestMongoDB(t)
+	if err := m.CreateNodePortTrafficIfNotExist(context.Background()); err != nil {
+		t.Fatalf("failed to create nodeport traffic: %v", err)
+	}
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(time.Hour)
+	if err := m.InsertNodePortTraffic(context.Background(),
+		&resources.NodePortTraffic{Time: startTime, Namespace: "ns-test", Name: "devbox1", Node: "node1", NodePort: 30001, IngressBytes: 10, EgressBytes: 3 << 20},
+		&resources.NodePortTraffic{Time: startTime.Add(time.Minute), Namespace: "ns-test", Name: "devbox1", Node: "node2", NodePort: 30002, IngressBytes: 20, EgressBytes: 1 << 20},
+		&resources.NodePortTraffic{Time: startTime, Namespace: "ns-other", Name: "devbox2", IngressBytes: 5},
+		&resources.NodePortTraffic{Time: endTime, Namespace: "ns-test", Name: "devbox1", IngressBytes: 100},
+	); err != nil {
+		t.Fatalf("failed to insert nodeport traffic: %v", err)
+	}
+	traffic, err := m.GetNodePortTraffic(context.Background(), "ns-test", startTime, endTime)
+	if err != nil {
+		t.Fatalf("failed to get nodeport traffic: %v", err)
+	}
+	if len(traffic) != 1 || traffic[0].IngressBytes != 30 || traffic[0].EgressBytes != 4<<20 {
+		t.Fatalf("GetNodePortTraffic() = %+v", traffic)
+	}
+	if all, err := m.GetNodePortTraffic(context.Background(), "", startTime, endTime); err != nil || len(all) != 2 {
+		t.Fatalf("GetNodePortTraffic() all namespaces = %+v, %v", all, err)
+	}
+
+	// the nodeport traffic is storage only and not billed
+	ids, amount, err := m.GenerateBillingData(context.Background(), startTime, endTime, testPropertyTypeLS(map[string]float64{resources.DevboxNetwork: 10}), []string{"ns-test"}, "owner1")
+	if err != nil {
+		t.Fatalf("failed to generate billing data: %v", err)
+	}
+	if amount != 0 || len(ids) != 0 {
+		t.Errorf("GenerateBillingData() = %v, %d, want no billing", ids, amount)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+// GetBillingUsage returns the usage of the billing that calculateBillings generates for [startTime, endTime) and the usage
+// of the consumption billing stored at endTime. Cloud VM billing is not generated from monitors and is left out.
+func (m *mongoDB) GetBillingUsage(ctx context.Context, startTime, endTime time.Time, prols *resources.PropertyTypeLS, namespaces []string, owner string) (map[database.BillingUsageKey]int64, map[database.BillingUsageKey]database.BillingUsage, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	// the same path as the billing generation, so apps whose usage prices at zero are not expected
+	billings, err := m.calculateBillings(ctx, startTime, endTime, prols, namespaces, owner)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to aggregate usage: %w", err)
+	}
+	expected := make(map[database.BillingUsageKey]int64)
+	for _, billing := range billings {
+		for _, appCost := range billing.AppCosts {
+			for property, used := range appCost.Used {
+				if prop, ok := prols.EnumMap[property]; ok && prop.HasPrice() && used != 0 {
+					expected[database.BillingUsageKey{Namespace: billing.Namespace, AppType: billing.AppType, Property: property}] += used
+				}
+			}
+		}
+	}
+
+	filter := bson.M{
+		"owner":     owner,
+		"type":      accountv1.Consumption,
+		"time":      endTime.UTC(),
+		"namespace": bson.M{"$in": namespaces},
+		"app_type":  bson.M{"$ne": resources.AppType[resources.CVM]},
+	}
+	cursor, err := m.getBillingCollection().Find(ctx, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find billing: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	billed := make(map[database.BillingUsageKey]database.BillingUsage)
+	for cursor.Next(ctx) {
+		var billing resources.Billing
+		if err := cursor.Decode(&billing); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode billing: %w", err)
+		}
+		for _, appCost := range billing.AppCosts {
+			for property, used := range appCost.Used {
+				if prop, ok := prols.EnumMap[property]; !ok || !prop.HasPrice() {
+					continue
+				}
+				key := database.BillingUsageKey{Namespace: billing.Namespace, AppType: billing.AppType, Property: property}
+				usage := billed[key]
+				usage.Used += used
+				usage.Amount += appCost.UsedAmount[property]
+				billed[key] = usage
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return expected, billed, nil
+}
@@ -0,0 +1,102 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+func TestMongoDB_GetBillingUsage(t *testing.T) {
+	m := newTestMongoDB(t)
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prols := resources.DefaultPropertyTypeLS
+	cpu := prols.StringMap["cpu"].Enum
+	insertMonitors := func(namespace, name string, from time.Time, minutes int) {
+		for i := 0; i < minutes; i++ {
+			if err := m.InsertMonitor(context.Background(), &resources.Monitor{
+				Time:     from.Add(time.Duration(i) * time.Minute),
+				Category: namespace,
+				Type:     resources.AppType[resources.APP],
+				Name:     name,
+				Used:     resources.EnumUsedMap{cpu: 1000},
+			}); err != nil {
+				t.Fatalf("failed to insert monitor: %v", err)
+			}
+		}
+	}
+	insertMonitors("ns-test", "app1", startTime, 120)
+	// only the first hour is billed
+	_, amount, err := m.GenerateBillingData(context.Background(), startTime, startTime.Add(time.Hour), prols, []string{"ns-test"}, "owner1")
+	if err != nil || amount == 0 {
+		t.Fatalf("GenerateBillingData() = %d, %v", amount, err)
+	}
+	key := database.BillingUsageKey{Namespace: "ns-test", AppType: resources.AppType[resources.APP], Property: cpu}
+
+	expected, billed, err := m.GetBillingUsage(context.Background(), startTime, startTime.Add(time.Hour), prols, []string{"ns-test"}, "owner1")
+	if err != nil || len(expected) != 1 || expected[key] != 1000 || len(billed) != 1 || billed[key].Used != 1000 || billed[key].Amount != amount {
+		t.Fatalf("GetBillingUsage() billed window = %v, %v, %v", expected, billed, err)
+	}
+	// a price change since the billing does not change the usage
+	repriced := testPropertyTypeLS(map[string]float64{"cpu": 2 * prols.StringMap["cpu"].UnitPrice})
+	expected, billed, err = m.GetBillingUsage(context.Background(), startTime, startTime.Add(time.Hour), repriced, []string{"ns-test"}, "owner1")
+	if err != nil || expected[key] != 1000 || billed[key].Used != 1000 || billed[key].Amount != amount {
+		t.Fatalf("GetBillingUsage() repriced window = %v, %v, %v", expected, billed, err)
+	}
+	expected, billed, err = m.GetBillingUsage(context.Background(), startTime.Add(time.Hour), startTime.Add(2*time.Hour), prols, []string{"ns-test"}, "owner1")
+	if err != nil || expected[key] != 1000 || len(billed) != 0 {
+		t.Errorf("GetBillingUsage() unbilled window = %v, %v, %v, want expected 1000 without billing", expected, billed, err)
+	}
+
+	insertMonitors("ns-test", "app2", startTime, 60)
+	expected, billed, err = m.GetBillingUsage(context.Background(), startTime, startTime.Add(time.Hour), prols, []string{"ns-test"}, "owner1")
+	if err != nil || expected[key] != 2000 || billed[key].Used != 1000 {
+		t.Errorf("GetBillingUsage() late monitors = %v, %v, %v, want expected 2000 and billed 1000", expected, billed, err)
+	}
+}
+
+func TestMongoDB_GetBillingUsage_FreeTier(t *testing.T) {
+	m := newTestMongoDB(t)
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prols := testPropertyTypeLS(nil)
+	cpu := prols.StringMap["cpu"]
+	// the first 2000 cpu of the window are free
+	cpu.Tiers = []resources.PriceTier{{UpTo: 2000, UnitPrice: 0}, {UnitPrice: 1}}
+	prols.StringMap["cpu"], prols.EnumMap[cpu.Enum] = cpu, cpu
+	for i := 0; i < 60; i++ {
+		if err := m.InsertMonitor(context.Background(), &resources.Monitor{
+			Time:     startTime.Add(time.Duration(i) * time.Minute),
+			Category: "ns-free",
+			Type:     resources.AppType[resources.APP],
+			Name:     "app1",
+			Used:     resources.EnumUsedMap{cpu.Enum: 1000},
+		}); err != nil {
+			t.Fatalf("failed to insert monitor: %v", err)
+		}
+	}
+	_, amount, err := m.GenerateBillingData(context.Background(), startTime, startTime.Add(time.Hour), prols, []string{"ns-free"}, "owner1")
+	if err != nil || amount != 0 {
+		t.Fatalf("GenerateBillingData() = %d, %v, want no billing", amount, err)
+	}
+	// no billing is generated for the free usage, so none is expected
+	expected, billed, err := m.GetBillingUsage(context.Background(), startTime, startTime.Add(time.Hour), prols, []string{"ns-free"}, "owner1")
+	if err != nil || len(expected) != 0 || len(billed) != 0 {
+		t.Errorf("GetBillingUsage() free tier = %v, %v, %v, want no usage", expected, billed, err)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+func (m *mongoDB) SetSpendingCap(ctx context.Context, spendingCap *resources.SpendingCap) error {
+	if spendingCap.Owner == "" {
+		return fmt.Errorf("owner is empty")
+	}
+	if spendingCap.MonthlyLimit <= 0 {
+		return fmt.Errorf("monthly limit must be positive")
+	}
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	spendingCap.UpdatedAt = time.Now().UTC()
+	_, err := m.getSpendingCapCollection().ReplaceOne(ctx, bson.M{"owner": spendingCap.Owner}, spendingCap, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save spending cap: %w", err)
+	}
+	// the recommendations were made against the old cap, the next evaluation makes a new one if still needed
+	return m.deleteSuspendRecommendations(ctx, bson.M{"owner": spendingCap.Owner})
+}
+
+func (m *mongoDB) GetSpendingCap(ctx context.Context, owner string) (*resources.SpendingCap, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	var spendingCap resources.SpendingCap
+	if err := m.getSpendingCapCollection().FindOne(ctx, bson.M{"owner": owner}).Decode(&spendingCap); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get spending cap: %w", err)
+	}
+	return &spendingCap, nil
+}
+
+func (m *mongoDB) DeleteSpendingCap(ctx context.Context, owner string) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	if _, err := m.getSpendingCapCollection().DeleteOne(ctx, bson.M{"owner": owner}); err != nil {
+		return fmt.Errorf("failed to delete spending cap: %w", err)
+	}
+	return m.deleteSuspendRecommendations(ctx, bson.M{"owner": owner})
+}
+
+func (m *mongoDB) deleteSuspendRecommendations(ctx context.Context, filter bson.M) error {
+	if _, err := m.getSuspendRecommendationCollection().DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete suspend recommendations: %w", err)
+	}
+	return nil
+}
+
+func (m *mongoDB) EvaluateSpendingCap(ctx context.Context, owner string, at time.Time) (*resources.SuspendRecommendation, error) {
+	spendingCap, err := m.GetSpendingCap(ctx, owner)
+	if err != nil || spendingCap == nil {
+		return nil, err
+	}
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	start, end := resources.InvoicePeriod(at)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"owner": owner,
+			"type":  accountv1.Consumption,
+			"time": bson.M{
+				"$gte": start,
+				"$lt":  end,
+			},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    nil,
+			"amount": bson.M{"$sum": "$amount"},
+		}}},
+	}
+	cursor, err := m.getBillingCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute aggregate query: %w", err)
+	}
+	defer cursor.Close(ctx)
+	var spent int64
+	if cursor.Next(ctx) {
+		var result struct {
+			Amount int64 `bson:"amount"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode consumption: %w", err)
+		}
+		spent = result.Amount
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	if spent <= spendingCap.MonthlyLimit {
+		// eg: a refund brought the consumption back within the cap
+		return nil, m.deleteSuspendRecommendations(ctx, bson.M{"owner": owner, "period_start": start})
+	}
+
+	at = at.UTC()
+	// the first evaluation over the cap fixes the suspend time of the period
+	update := bson.M{
+		"$set": bson.M{
+			"limit": spendingCap.MonthlyLimit,
+			"spent": spent,
+		},
+		"$setOnInsert": bson.M{
+			"created_at": at,
+			"suspend_at": at.Add(spendingCap.GracePeriod()),
+		},
+	}
+	var recommendation resources.SuspendRecommendation
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err = m.getSuspendRecommendationCollection().FindOneAndUpdate(ctx, bson.M{"owner": owner, "period_start": start}, update, opts).Decode(&recommendation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save suspend recommendation: %w", err)
+	}
+	return &recommendation, nil
+}
+
+func (m *mongoDB) GetDueSuspendRecommendations(ctx context.Context, now time.Time) ([]resources.SuspendRecommendation, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	start, _ := resources.InvoicePeriod(now)
+	filter := bson.M{
+		"period_start": start,
+		"suspend_at":   bson.M{"$lte": now.UTC()},
+	}
+	cursor, err := m.getSuspendRecommendationCollection().Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find suspend recommendations: %w", err)
+	}
+	defer cursor.Close(ctx)
+	var recommendations []resources.SuspendRecommendation
+	if err := cursor.All(ctx, &recommendations); err != nil {
+		return nil, fmt.Errorf("failed to decode suspend recommendations: %w", err)
+	}
+	return recommendations, nil
+}
+
+func (m *mongoDB) CreateSpendingCapIfNotExist(ctx context.Context) error {
+	for _, c := range []struct {
+		name    string
+		indexes []mongo.IndexModel
+	}{
+		{m.SpendingCapConn, spendingCapIndexes},
+		{m.SuspendConn, suspendRecommendationIndexes},
+	} {
+		if exist, err := m.collectionExist(ctx, m.AccountDB, c.name); err != nil {
+			return err
+		} else if exist {
+			continue
+		}
+		ctx, cancel := m.operationContext(ctx)
+		err := m.Client.Database(m.AccountDB).CreateCollection(ctx, c.name)
+		if err == nil {
+			_, err = m.Client.Database(m.AccountDB).Collection(c.name).Indexes().CreateMany(ctx, c.indexes)
+		}
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to create collection %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+func (m *mongoDB) getSpendingCapCollection() *mongo.Collection {
+	return m.Client.Database(m.AccountDB).Collection(m.SpendingCapConn)
+}
+
+func (m *mongoDB) getSuspendRecommendationCollection() *mongo.Collection {
+	return m.Client.Database(m.AccountDB).Collection(m.SuspendConn)
+}
@@ -0,0 +1,111 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+func TestMongoDB_EvaluateSpendingCap(t *testing.T) {
+	m := newTestMongoDB(t)
+	if err := m.CreateSpendingCapIfNotExist(context.Background()); err != nil {
+		t.Fatalf("failed to create spending cap: %v", err)
+	}
+	at := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	if err := m.SetSpendingCap(context.Background(), &resources.SpendingCap{Owner: "owner1", MonthlyLimit: 100, GracePeriodSeconds: 3600}); err != nil {
+		t.Fatalf("failed to set spending cap: %v", err)
+	}
+	if err := m.SaveBillings(context.Background(), &resources.Billing{OrderID: "order1", Owner: "owner1", Type: accountv1.Consumption, Amount: 80, Time: at}); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+	if recommendation, err := m.EvaluateSpendingCap(context.Background(), "owner1", at); err != nil || recommendation != nil {
+		t.Fatalf("EvaluateSpendingCap() within cap = %+v, %v", recommendation, err)
+	}
+
+	if err := m.SaveBillings(context.Background(), &resources.Billing{OrderID: "order2", Owner: "owner1", Type: accountv1.Consumption, Amount: 30, Time: at}); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+	recommendation, err := m.EvaluateSpendingCap(context.Background(), "owner1", at)
+	if err != nil || recommendation == nil || recommendation.Spent != 110 || !recommendation.SuspendAt.Equal(at.Add(time.Hour)) {
+		t.Fatalf("EvaluateSpendingCap() over cap = %+v, %v", recommendation, err)
+	}
+	// evaluating again keeps the suspend time
+	if recommendation, err = m.EvaluateSpendingCap(context.Background(), "owner1", at.Add(time.Hour)); err != nil || recommendation == nil || !recommendation.SuspendAt.Equal(at.Add(time.Hour)) {
+		t.Fatalf("EvaluateSpendingCap() again = %+v, %v", recommendation, err)
+	}
+
+	due, err := m.GetDueSuspendRecommendations(context.Background(), at.Add(30*time.Minute))
+	if err != nil || len(due) != 0 {
+		t.Fatalf("GetDueSuspendRecommendations() in grace period = %+v, %v", due, err)
+	}
+	due, err = m.GetDueSuspendRecommendations(context.Background(), at.Add(time.Hour))
+	if err != nil || len(due) != 1 || due[0].Owner != "owner1" {
+		t.Errorf("GetDueSuspendRecommendations() after grace period = %+v, %v", due, err)
+	}
+}
+
+func TestMongoDB_SuspendRecommendationResolved(t *testing.T) {
+	m := newTestMongoDB(t)
+	if err := m.CreateSpendingCapIfNotExist(context.Background()); err != nil {
+		t.Fatalf("failed to create spending cap: %v", err)
+	}
+	at := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	overCap := func() {
+		t.Helper()
+		if err := m.SetSpendingCap(context.Background(), &resources.SpendingCap{Owner: "owner1", MonthlyLimit: 100}); err != nil {
+			t.Fatalf("failed to set spending cap: %v", err)
+		}
+		if recommendation, err := m.EvaluateSpendingCap(context.Background(), "owner1", at); err != nil || recommendation == nil {
+			t.Fatalf("EvaluateSpendingCap() over cap = %+v, %v", recommendation, err)
+		}
+	}
+	assertNoneDue := func(name string) {
+		t.Helper()
+		due, err := m.GetDueSuspendRecommendations(context.Background(), at.Add(resources.DefaultSpendingCapGracePeriod))
+		if err != nil || len(due) != 0 {
+			t.Errorf("GetDueSuspendRecommendations() after %s = %+v, %v", name, due, err)
+		}
+	}
+	if err := m.SaveBillings(context.Background(), &resources.Billing{OrderID: "order1", Owner: "owner1", Type: accountv1.Consumption, Amount: 150, Time: at}); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+
+	overCap()
+	if err := m.SetSpendingCap(context.Background(), &resources.SpendingCap{Owner: "owner1", MonthlyLimit: 200}); err != nil {
+		t.Fatalf("failed to raise spending cap: %v", err)
+	}
+	assertNoneDue("raising the cap")
+
+	overCap()
+	if err := m.DeleteSpendingCap(context.Background(), "owner1"); err != nil {
+		t.Fatalf("failed to delete spending cap: %v", err)
+	}
+	assertNoneDue("deleting the cap")
+
+	overCap()
+	// a refund brings the consumption back within the cap
+	if err := m.SaveBillings(context.Background(), &resources.Billing{OrderID: "order2", Owner: "owner1", Type: accountv1.Consumption, Amount: -60, Time: at}); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+	if recommendation, err := m.EvaluateSpendingCap(context.Background(), "owner1", at.Add(time.Hour)); err != nil || recommendation != nil {
+		t.Fatalf("EvaluateSpendingCap() within cap = %+v, %v", recommendation, err)
+	}
+	assertNoneDue("spending within the cap")
+}
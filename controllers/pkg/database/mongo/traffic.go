@@ -16,10 +16,14 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 /* example:
@@ -39,23 +43,25 @@ import (
   }
 */
 
-func (m *mongoDB) GetTrafficRecvBytes(startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error) {
-	return m.getTrafficBytes(false, startTime, endTime, namespace, _type, name)
+func (m *mongoDB) GetTrafficRecvBytes(ctx context.Context, startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error) {
+	return m.getTrafficBytes(ctx, false, startTime, endTime, namespace, _type, name)
 }
 
-func (m *mongoDB) GetTrafficSentBytes(startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error) {
-	return m.getTrafficBytes(true, startTime, endTime, namespace, _type, name)
+func (m *mongoDB) GetTrafficSentBytes(ctx context.Context, startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error) {
+	return m.getTrafficBytes(ctx, true, startTime, endTime, namespace, _type, name)
 }
 
-func (m *mongoDB) GetPodTrafficSentBytes(startTime, endTime time.Time, namespace string, name string) (int64, error) {
-	return m.getPodTrafficBytes(true, startTime, endTime, namespace, name)
+func (m *mongoDB) GetPodTrafficSentBytes(ctx context.Context, startTime, endTime time.Time, namespace string, name string) (int64, error) {
+	return m.getPodTrafficBytes(ctx, true, startTime, endTime, namespace, name)
 }
 
-func (m *mongoDB) GetPodTrafficRecvBytes(startTime, endTime time.Time, namespace string, name string) (int64, error) {
-	return m.getPodTrafficBytes(false, startTime, endTime, namespace, name)
+func (m *mongoDB) GetPodTrafficRecvBytes(ctx context.Context, startTime, endTime time.Time, namespace string, name string) (int64, error) {
+	return m.getPodTrafficBytes(ctx, false, startTime, endTime, namespace, name)
 }
 
-func (m *mongoDB) getPodTrafficBytes(sent bool, startTime, endTime time.Time, namespace string, name string) (int64, error) {
+func (m *mongoDB) getPodTrafficBytes(ctx context.Context, sent bool, startTime, endTime time.Time, namespace string, name string) (int64, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	filter := bson.M{
 		"traffic_meta.pod_namespace": namespace,
 		"traffic_meta.pod_name":      name,
@@ -72,13 +78,13 @@ func (m *mongoDB) getPodTrafficBytes(sent bool, startTime, endTime time.Time, na
 	} else {
 		pipeline = append(pipeline, bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: nil}, {Key: "total", Value: bson.D{{Key: "$sum", Value: "$recv_bytes"}}}}}})
 	}
-	cur, err := m.getTrafficCollection().Aggregate(context.Background(), pipeline)
+	cur, err := m.getTrafficCollection().Aggregate(ctx, pipeline)
 	if err != nil {
 		return 0, err
 	}
-	defer cur.Close(context.Background())
+	defer cur.Close(ctx)
 	total := int64(0)
-	for cur.Next(context.Background()) {
+	for cur.Next(ctx) {
 		var result struct {
 			Total int64 `bson:"total"`
 		}
@@ -90,7 +96,9 @@ func (m *mongoDB) getPodTrafficBytes(sent bool, startTime, endTime time.Time, na
 	return total, nil
 }
 
-func (m *mongoDB) getTrafficBytes(sent bool, startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error) {
+func (m *mongoDB) getTrafficBytes(ctx context.Context, sent bool, startTime, endTime time.Time, namespace string, _type uint8, name string) (int64, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
 	filter := bson.M{
 		"traffic_meta.pod_namespace": namespace,
 		"traffic_meta.pod_type":      _type,
@@ -108,13 +116,13 @@ func (m *mongoDB) getTrafficBytes(sent bool, startTime, endTime time.Time, names
 	} else {
 		pipeline = append(pipeline, bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: nil}, {Key: "total", Value: bson.D{{Key: "$sum", Value: "$recv_bytes"}}}}}})
 	}
-	cur, err := m.getTrafficCollection().Aggregate(context.Background(), pipeline)
+	cur, err := m.getTrafficCollection().Aggregate(ctx, pipeline)
 	if err != nil {
 		return 0, err
 	}
-	defer cur.Close(context.Background())
+	defer cur.Close(ctx)
 	total := int64(0)
-	for cur.Next(context.Background()) {
+	for cur.Next(ctx) {
 		var result struct {
 			Total int64 `bson:"total"`
 		}
@@ -126,6 +134,135 @@ func (m *mongoDB) getTrafficBytes(sent bool, startTime, endTime time.Time, names
 	return total, nil
 }
 
+// GetTrafficTTL returns the expireAfterSeconds option of the traffic time series collection.
+func (m *mongoDB) GetTrafficTTL(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	cur, err := m.Client.Database(m.TrafficDB).ListCollections(ctx, bson.M{"name": m.TrafficConn})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list collections: %v", err)
+	}
+	defer cur.Close(ctx)
+	if !cur.Next(ctx) {
+		return 0, fmt.Errorf("collection %s/%s not found", m.TrafficDB, m.TrafficConn)
+	}
+	var result struct {
+		Options struct {
+			ExpireAfterSeconds *int64 `bson:"expireAfterSeconds"`
+		} `bson:"options"`
+	}
+	if err := cur.Decode(&result); err != nil {
+		return 0, fmt.Errorf("decode error: %v", err)
+	}
+	if result.Options.ExpireAfterSeconds == nil {
+		return 0, nil
+	}
+	return time.Duration(*result.Options.ExpireAfterSeconds) * time.Second, nil
+}
+
+// SetTrafficTTL modifies the expireAfterSeconds of the traffic time series collection by collMod,
+// a zero ttl disables the expiration.
+func (m *mongoDB) SetTrafficTTL(ctx context.Context, ttl time.Duration) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	var expire interface{} = "off"
+	if ttl > 0 {
+		expire = int64(ttl.Seconds())
+	}
+	cmd := bson.D{
+		primitive.E{Key: "collMod", Value: m.TrafficConn},
+		primitive.E{Key: "expireAfterSeconds", Value: expire},
+	}
+	if err := m.Client.Database(m.TrafficDB).RunCommand(ctx, cmd).Err(); err != nil {
+		return fmt.Errorf("failed to set traffic ttl: %v", err)
+	}
+	return nil
+}
+
+// CreateTrafficIndexes creates the compound indexes used by the traffic bytes queries.
+func (m *mongoDB) CreateTrafficIndexes(ctx context.Context) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	_, err := m.getTrafficCollection().Indexes().CreateMany(ctx, trafficIndexes)
+	if err != nil {
+		return fmt.Errorf("failed to create index for traffic: %v", err)
+	}
+	return nil
+}
+
+// archiveBatchSize is the number of traffic documents inserted into the archive at once.
+const archiveBatchSize = 1000
+
+// ArchiveTrafficBefore copies the traffic documents older than before into the archive collection,
+// so that they are kept after the time series ttl removes them. The documents keep their _id, so
+// re-archiving the same window or a concurrent run only counts the documents it inserted itself.
+// The copy can take long on a large window, it is bounded by ctx and each batch by the operation timeout.
+func (m *mongoDB) ArchiveTrafficBefore(ctx context.Context, before time.Time) (int64, error) {
+	filter := bson.M{
+		"timestamp": bson.M{
+			"$lt": before.UTC(),
+		},
+	}
+	cursor, err := m.getTrafficCollection().Find(ctx, filter, options.Find().SetBatchSize(archiveBatchSize))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find traffic to archive: %v", err)
+	}
+	defer cursor.Close(ctx)
+	var (
+		archived int64
+		batch    = make([]interface{}, 0, archiveBatchSize)
+	)
+	for cursor.Next(ctx) {
+		batch = append(batch, bson.Raw(append([]byte(nil), cursor.Current...)))
+		if len(batch) < archiveBatchSize {
+			continue
+		}
+		inserted, err := m.insertTrafficArchive(ctx, batch)
+		archived += inserted
+		if err != nil {
+			return archived, err
+		}
+		batch = batch[:0]
+	}
+	if err := cursor.Err(); err != nil {
+		return archived, fmt.Errorf("failed to read traffic to archive: %v", err)
+	}
+	if len(batch) != 0 {
+		inserted, err := m.insertTrafficArchive(ctx, batch)
+		archived += inserted
+		if err != nil {
+			return archived, err
+		}
+	}
+	return archived, nil
+}
+
+// insertTrafficArchive inserts the batch into the archive and returns the number of inserted documents,
+// the documents that are already archived are skipped.
+func (m *mongoDB) insertTrafficArchive(ctx context.Context, batch []interface{}) (int64, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	_, err := m.getTrafficArchiveCollection().InsertMany(ctx, batch, options.InsertMany().SetOrdered(false))
+	if err == nil {
+		return int64(len(batch)), nil
+	}
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return 0, fmt.Errorf("failed to archive traffic: %v", err)
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		// E11000 duplicate key
+		if writeErr.Code != 11000 {
+			return 0, fmt.Errorf("failed to archive traffic: %v", err)
+		}
+	}
+	return int64(len(batch) - len(bulkErr.WriteErrors)), nil
+}
+
 func (m *mongoDB) getTrafficCollection() *mongo.Collection {
 	return m.Client.Database(m.TrafficDB).Collection(m.TrafficConn)
 }
+
+func (m *mongoDB) getTrafficArchiveCollection() *mongo.Collection {
+	return m.Client.Database(m.TrafficDB).Collection(m.TrafficArchive)
+}
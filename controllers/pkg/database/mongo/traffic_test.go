@@ -14,6 +14,33 @@
 
 package mongo
 
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoDB_ArchiveTrafficBefore(t *testing.T) {
+	m := newTestMongoDB(t)
+	before := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if _, err := m.getTrafficCollection().InsertMany(context.Background(), []interface{}{
+		bson.M{"timestamp": before.Add(-2 * time.Hour), "sent_bytes": 1},
+		bson.M{"timestamp": before.Add(-time.Hour), "sent_bytes": 2},
+		bson.M{"timestamp": before, "sent_bytes": 3},
+	}); err != nil {
+		t.Fatalf("failed to insert traffic: %v", err)
+	}
+	if count, err := m.ArchiveTrafficBefore(context.Background(), before); err != nil || count != 2 {
+		t.Fatalf("ArchiveTrafficBefore() = %d, %v, want 2", count, err)
+	}
+	// the archived documents are not counted again
+	if count, err := m.ArchiveTrafficBefore(context.Background(), before.Add(time.Hour)); err != nil || count != 1 {
+		t.Errorf("ArchiveTrafficBefore() again = %d, %v, want 1", count, err)
+	}
+}
+
 //import (
 //	"context"
 //	"os"
@@ -0,0 +1,161 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/resources"
+	"github.com/labring/sealos/controllers/pkg/utils/logger"
+)
+
+// watchAllOwners is the resume token key of a watch without owner filter
+const watchAllOwners = "*"
+
+// errCodeChangeStreamHistoryLost is returned when the resume token is no longer in the oplog
+const errCodeChangeStreamHistoryLost = 286
+
+// WatchBillings opens a change stream on the billing collection. The resume token of every acknowledged event
+// is persisted in the billing watch collection, so a consumer restarted with the same owner continues after
+// the last event it processed.
+func (m *mongoDB) WatchBillings(ctx context.Context, owner string) (<-chan database.BillingEvent, error) {
+	match := bson.M{
+		"operationType": bson.M{"$in": bson.A{
+			string(database.BillingEventInsert),
+			string(database.BillingEventUpdate),
+			string(database.BillingEventReplace),
+		}},
+	}
+	key := watchAllOwners
+	if owner != "" {
+		match["fullDocument.owner"] = owner
+		key = owner
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	token, tokenTime, err := m.getBillingResumeToken(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get billing resume token: %w", err)
+	}
+	if token != nil {
+		opts.SetResumeAfter(token)
+	}
+	stream, err := m.getBillingCollection().Watch(ctx, pipeline, opts)
+	if token != nil && isChangeStreamHistoryLost(err) {
+		// the token has aged out of the oplog, the events since it are lost and can not be replayed
+		logger.Warn("billing resume token of %s saved at %s is no longer in the oplog, billing changes since then are skipped", key, tokenTime.Format(time.RFC3339))
+		if err := m.deleteBillingResumeToken(ctx, key); err != nil {
+			return nil, fmt.Errorf("failed to delete billing resume token: %w", err)
+		}
+		stream, err = m.getBillingCollection().Watch(ctx, pipeline, opts.SetResumeAfter(nil))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch billing: %w", err)
+	}
+
+	events := make(chan database.BillingEvent)
+	ack := make(chan struct{}, 1)
+	go func() {
+		defer close(events)
+		defer stream.Close(context.Background())
+		for stream.Next(ctx) {
+			var change struct {
+				OperationType string            `bson:"operationType"`
+				FullDocument  resources.Billing `bson:"fullDocument"`
+			}
+			if err := stream.Decode(&change); err != nil {
+				sendBillingEvent(ctx, events, database.BillingEvent{Err: fmt.Errorf("decode error: %w", err)})
+				return
+			}
+			if !sendBillingEvent(ctx, events, database.NewBillingEvent(database.BillingEventType(change.OperationType), change.FullDocument, ack)) {
+				return
+			}
+			select {
+			case <-ack:
+			case <-ctx.Done():
+				return
+			}
+			if err := m.saveBillingResumeToken(ctx, key, stream.ResumeToken()); err != nil {
+				logger.Error("failed to save billing resume token of %s: %v", key, err)
+			}
+		}
+		if err := stream.Err(); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			sendBillingEvent(ctx, events, database.BillingEvent{Err: fmt.Errorf("change stream error: %w", err)})
+		}
+	}()
+	return events, nil
+}
+
+func sendBillingEvent(ctx context.Context, events chan<- database.BillingEvent, event database.BillingEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func isChangeStreamHistoryLost(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(errCodeChangeStreamHistoryLost)
+}
+
+func (m *mongoDB) getBillingResumeToken(ctx context.Context, key string) (bson.Raw, time.Time, error) {
+	var result struct {
+		Token bson.Raw  `bson:"token"`
+		Time  time.Time `bson:"time"`
+	}
+	err := m.getBillingWatchCollection().FindOne(ctx, bson.M{"_id": key}).Decode(&result)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, time.Time{}, nil
+		}
+		return nil, time.Time{}, err
+	}
+	return result.Token, result.Time, nil
+}
+
+func (m *mongoDB) deleteBillingResumeToken(ctx context.Context, key string) error {
+	_, err := m.getBillingWatchCollection().DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+func (m *mongoDB) saveBillingResumeToken(ctx context.Context, key string, token bson.Raw) error {
+	if token == nil {
+		return nil
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"token": token,
+			"time":  time.Now().UTC(),
+		},
+	}
+	_, err := m.getBillingWatchCollection().UpdateOne(ctx, bson.M{"_id": key}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (m *mongoDB) getBillingWatchCollection() *mongo.Collection {
+	return m.Client.Database(m.AccountDB).Collection(m.BillingWatchConn)
+}
@@ -0,0 +1,146 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package invoice
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+// amount unit: 1000000 = 1¥
+const amountUnit = 1000000
+
+// FormatAmount formats the amount in yuan with the full precision of the unit.
+func FormatAmount(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%06d", sign, amount/amountUnit, amount%amountUnit)
+}
+
+// lastDay is the last billed day of the invoice, the period end is exclusive.
+func lastDay(inv *resources.Invoice) time.Time {
+	return inv.PeriodEnd.AddDate(0, 0, -1)
+}
+
+// WriteCSV writes one row per invoice item followed by a total row, period_end is the last billed day.
+func WriteCSV(w io.Writer, inv *resources.Invoice) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{
+		{"number", "owner", "period_start", "period_end", "namespace", "app_type", "count", "amount"},
+	}
+	period := []string{inv.Number, inv.Owner, inv.PeriodStart.Format("2006-01-02"), lastDay(inv).Format("2006-01-02")}
+	for _, item := range inv.Items {
+		records = append(records, append(append([]string{}, period...), item.Namespace, item.AppType, strconv.FormatInt(item.Count, 10), FormatAmount(item.Amount)))
+	}
+	records = append(records, append(append([]string{}, period...), "", "TOTAL", "", FormatAmount(inv.Amount)))
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write invoice csv: %w", err)
+	}
+	return nil
+}
+
+const (
+	pdfLinesPerPage = 50
+	pdfFontSize     = 10
+	pdfLeading      = 14
+)
+
+// WritePDF renders the invoice as a plain text PDF document using the standard Courier font,
+// so no font or third-party PDF library is required.
+func WritePDF(w io.Writer, inv *resources.Invoice) error {
+	lines := []string{
+		"INVOICE " + inv.Number,
+		"Owner:  " + inv.Owner,
+		fmt.Sprintf("Period: %s - %s", inv.PeriodStart.Format("2006-01-02"), lastDay(inv).Format("2006-01-02")),
+		"",
+		fmt.Sprintf("%-32s %-16s %8s %18s", "NAMESPACE", "APP TYPE", "COUNT", "AMOUNT"),
+	}
+	for _, item := range inv.Items {
+		lines = append(lines, fmt.Sprintf("%-32s %-16s %8d %18s", item.Namespace, item.AppType, item.Count, FormatAmount(item.Amount)))
+	}
+	lines = append(lines, "", fmt.Sprintf("%-58s %18s", "TOTAL", FormatAmount(inv.Amount)))
+
+	var pages [][]string
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// object layout: 1 catalog, 2 pages, 3 font, then a page and its content stream per page
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>")
+	for i, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL 40 800 Td\n", pdfFontSize, pdfLeading)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", escapePDFText(line))
+		}
+		content.WriteString("ET")
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write invoice pdf: %w", err)
+	}
+	return nil
+}
+
+// escapePDFText escapes a PDF literal string, characters outside ASCII are replaced as the standard fonts cannot show them.
+func escapePDFText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteRune('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
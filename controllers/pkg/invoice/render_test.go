@@ -0,0 +1,93 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package invoice
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+func testInvoice() *resources.Invoice {
+	start, end := resources.InvoicePeriod(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC))
+	return &resources.Invoice{
+		Owner:       "owner1",
+		Sequence:    1,
+		Number:      resources.InvoiceNumber(start, 1),
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Items: []resources.InvoiceItem{
+			{Namespace: "ns-a", AppType: resources.APP, Count: 2, Amount: 1500000},
+			{Namespace: "ns-b", AppType: resources.DB, Count: 1, Amount: 25},
+		},
+		Amount: 1500025,
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := map[int64]string{
+		0:        "0.000000",
+		25:       "0.000025",
+		1500000:  "1.500000",
+		-1000001: "-1.000001",
+	}
+	for amount, want := range tests {
+		if got := FormatAmount(amount); got != want {
+			t.Errorf("FormatAmount(%d) = %s, want %s", amount, got, want)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testInvoice()); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	want := `number,owner,period_start,period_end,namespace,app_type,count,amount
+INV-202401-000001,owner1,2024-01-01,2024-01-31,ns-a,APP,2,1.500000
+INV-202401-000001,owner1,2024-01-01,2024-01-31,ns-b,DB,1,0.000025
+INV-202401-000001,owner1,2024-01-01,2024-01-31,,TOTAL,,1.500025
+`
+	if buf.String() != want {
+		t.Errorf("WriteCSV() = %s, want %s", buf.String(), want)
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	inv := testInvoice()
+	for i := 0; i < 100; i++ {
+		inv.Items = append(inv.Items, resources.InvoiceItem{Namespace: "ns-(c)", AppType: resources.JOB, Count: 1, Amount: 1})
+	}
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, inv); err != nil {
+		t.Fatalf("WritePDF() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "%PDF-1.4\n") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatalf("WritePDF() output is not a pdf document")
+	}
+	if !strings.Contains(out, "/Count 3") {
+		t.Errorf("WritePDF() expected 3 pages")
+	}
+	if !strings.Contains(out, "Period: 2024-01-01 - 2024-01-31") {
+		t.Errorf("WritePDF() expected the last billed day as period end")
+	}
+	if !strings.Contains(out, `ns-\(c\)`) {
+		t.Errorf("WritePDF() parentheses are not escaped")
+	}
+}
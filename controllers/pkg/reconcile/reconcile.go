@@ -0,0 +1,232 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/invoice"
+	"github.com/labring/sealos/controllers/pkg/resources"
+	"github.com/labring/sealos/controllers/pkg/types"
+)
+
+// Deductor deducts the balance of a user, preDo runs in the same transaction before the deduction.
+type Deductor interface {
+	AddDeductionBalanceWithFunc(ops *types.UserQueryOpts, amount int64, preDo, postDo func() error) error
+}
+
+type Options struct {
+	Owner      string
+	Namespaces []string
+	// StartTime and EndTime are truncated to the hour, every hourly billing window in between is reconciled.
+	StartTime time.Time
+	EndTime   time.Time
+	// Correct issues a correction billing for every under-billed usage whose rate is known and deducts its amount
+	// with Deductor. Over-billed usage and usage of a property the window billed nothing for are only reported,
+	// they need a manual review.
+	Correct  bool
+	Deductor Deductor
+}
+
+// Discrepancy is the mismatch between the re-aggregated and the billed usage of one property of a namespace
+// and app type in one billing window. Usage rather than amount is compared, so that a price change since the window
+// was billed is not reported as a discrepancy.
+type Discrepancy struct {
+	Owner     string    `json:"owner" bson:"owner"`
+	Namespace string    `json:"namespace" bson:"namespace"`
+	AppType   uint8     `json:"appType" bson:"app_type"`
+	Property  uint8     `json:"property" bson:"property"`
+	Time      time.Time `json:"time" bson:"time"`
+	// Expected is the re-aggregated used value, Actual is the used value of the stored billing
+	Expected int64 `json:"expected" bson:"expected"`
+	Actual   int64 `json:"actual" bson:"actual"`
+	// Amount is Diff priced at the average rate the window was billed at for the property,
+	// zero if the window billed none of the property and the rate is unknown.
+	Amount int64 `json:"amount" bson:"amount"`
+}
+
+// Diff is positive when the billed usage is lower than the re-aggregated usage.
+func (d Discrepancy) Diff() int64 {
+	return d.Expected - d.Actual
+}
+
+// Correction is the billing issued for a discrepancy.
+type Correction struct {
+	Discrepancy Discrepancy
+	OrderID     string
+}
+
+type Report struct {
+	Discrepancies []Discrepancy
+	Corrections   []Correction
+	prols         *resources.PropertyTypeLS
+}
+
+// Run re-aggregates the usage of the billing windows of opts and compares it with the stored billing, the way the
+// billing controller generates them: one window per hour, billed at the end of the window. prols selects the compared
+// properties and leaves out the apps whose usage prices at zero, as the billing controller does, the differences are
+// priced at the rates the windows were billed at.
+func Run(ctx context.Context, db database.BillingStore, prols *resources.PropertyTypeLS, opts Options) (*Report, error) {
+	if opts.Owner == "" {
+		return nil, fmt.Errorf("owner is empty")
+	}
+	if opts.Correct && opts.Deductor == nil {
+		return nil, fmt.Errorf("deductor is required to correct billing")
+	}
+	report := &Report{prols: prols}
+	start, end := opts.StartTime.UTC().Truncate(time.Hour), opts.EndTime.UTC().Truncate(time.Hour)
+	for t := start.Add(time.Hour); !t.After(end); t = t.Add(time.Hour) {
+		expected, billed, err := db.GetBillingUsage(ctx, t.Add(-time.Hour), t, prols, opts.Namespaces, opts.Owner)
+		if err != nil {
+			return report, fmt.Errorf("failed to reconcile billing of %s: %w", t.Format(time.RFC3339), err)
+		}
+		discrepancies := Compare(opts.Owner, t, expected, billed)
+		report.Discrepancies = append(report.Discrepancies, discrepancies...)
+		if !opts.Correct {
+			continue
+		}
+		for _, discrepancy := range discrepancies {
+			if discrepancy.Diff() <= 0 || discrepancy.Amount <= 0 {
+				continue
+			}
+			id, err := correct(ctx, db, opts.Deductor, discrepancy)
+			if err != nil {
+				return report, err
+			}
+			report.Corrections = append(report.Corrections, Correction{Discrepancy: discrepancy, OrderID: id})
+		}
+	}
+	return report, nil
+}
+
+// Compare returns the discrepancies between the expected and the billed usage of the window ending at t,
+// sorted by namespace, app type and property. The differences are priced at the rates of the billed usage.
+func Compare(owner string, t time.Time, expected map[database.BillingUsageKey]int64, billed map[database.BillingUsageKey]database.BillingUsage) []Discrepancy {
+	var discrepancies []Discrepancy
+	for key, used := range expected {
+		if billed[key].Used != used {
+			discrepancies = append(discrepancies, newDiscrepancy(owner, t, key, used, billed[key]))
+		}
+	}
+	for key, usage := range billed {
+		if _, ok := expected[key]; !ok && usage.Used != 0 {
+			discrepancies = append(discrepancies, newDiscrepancy(owner, t, key, 0, usage))
+		}
+	}
+	sort.Slice(discrepancies, func(i, j int) bool {
+		if discrepancies[i].Namespace != discrepancies[j].Namespace {
+			return discrepancies[i].Namespace < discrepancies[j].Namespace
+		}
+		if discrepancies[i].AppType != discrepancies[j].AppType {
+			return discrepancies[i].AppType < discrepancies[j].AppType
+		}
+		return discrepancies[i].Property < discrepancies[j].Property
+	})
+	return discrepancies
+}
+
+func newDiscrepancy(owner string, t time.Time, key database.BillingUsageKey, expected int64, billed database.BillingUsage) Discrepancy {
+	d := Discrepancy{Owner: owner, Namespace: key.Namespace, AppType: key.AppType, Property: key.Property, Time: t, Expected: expected, Actual: billed.Used}
+	if billed.Used > 0 {
+		amount := math.Ceil(math.Abs(float64(d.Diff())) * float64(billed.Amount) / float64(billed.Used))
+		d.Amount = int64(math.Copysign(amount, float64(d.Diff())))
+	}
+	return d
+}
+
+// correct saves the correction billing with the missing usage at the window time, so reconciling the window again
+// reports no discrepancy.
+func correct(ctx context.Context, db database.BillingStore, deductor Deductor, discrepancy Discrepancy) (string, error) {
+	id, err := gonanoid.New(12)
+	if err != nil {
+		return "", fmt.Errorf("generate billing id error: %v", err)
+	}
+	billing := &resources.Billing{
+		OrderID:   id,
+		Type:      accountv1.Consumption,
+		Namespace: discrepancy.Namespace,
+		AppType:   discrepancy.AppType,
+		AppCosts: []resources.AppCost{{
+			Name:       "correction",
+			Used:       resources.EnumUsedMap{discrepancy.Property: discrepancy.Diff()},
+			UsedAmount: resources.EnumUsedMap{discrepancy.Property: discrepancy.Amount},
+			Amount:     discrepancy.Amount,
+		}},
+		Amount: discrepancy.Amount,
+		Owner:  discrepancy.Owner,
+		Time:   discrepancy.Time,
+		Status: resources.Settled,
+		Detail: fmt.Sprintf("billing correction: used %d, billed %d", discrepancy.Expected, discrepancy.Actual),
+	}
+	err = deductor.AddDeductionBalanceWithFunc(&types.UserQueryOpts{Owner: discrepancy.Owner}, billing.Amount, func() error {
+		if err := db.SaveBillings(ctx, billing); err != nil {
+			return fmt.Errorf("save billing failed: %v", err)
+		}
+		return nil
+	}, func() error {
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to correct billing of %s/%s: %w", discrepancy.Namespace, discrepancy.Time.Format(time.RFC3339), err)
+	}
+	return id, nil
+}
+
+// WriteCSV writes one row per discrepancy with the order id of its correction billing, if any.
+func (r *Report) WriteCSV(w io.Writer) error {
+	corrected := make(map[Discrepancy]string, len(r.Corrections))
+	for _, c := range r.Corrections {
+		corrected[c.Discrepancy] = c.OrderID
+	}
+	records := [][]string{
+		{"owner", "time", "namespace", "app_type", "property", "expected_used", "actual_used", "diff_used", "diff_amount", "correction_order_id"},
+	}
+	for _, d := range r.Discrepancies {
+		records = append(records, []string{d.Owner, d.Time.Format(time.RFC3339), d.Namespace, appTypeName(d.AppType), r.propertyName(d.Property),
+			strconv.FormatInt(d.Expected, 10), strconv.FormatInt(d.Actual, 10), strconv.FormatInt(d.Diff(), 10), invoice.FormatAmount(d.Amount), corrected[d]})
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write reconcile report: %w", err)
+	}
+	return nil
+}
+
+func appTypeName(tp uint8) string {
+	if name, ok := resources.AppTypeReverse[tp]; ok {
+		return name
+	}
+	return strconv.Itoa(int(tp))
+}
+
+func (r *Report) propertyName(property uint8) string {
+	if r.prols != nil {
+		if prop, ok := r.prols.EnumMap[property]; ok {
+			return prop.Name
+		}
+	}
+	return strconv.Itoa(int(property))
+}
@@ -0,0 +1,131 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/database"
+	"github.com/labring/sealos/controllers/pkg/database/fake"
+	"github.com/labring/sealos/controllers/pkg/resources"
+	"github.com/labring/sealos/controllers/pkg/types"
+)
+
+type fakeDeductor struct {
+	amount int64
+}
+
+func (f *fakeDeductor) AddDeductionBalanceWithFunc(_ *types.UserQueryOpts, amount int64, preDo, postDo func() error) error {
+	if err := preDo(); err != nil {
+		return err
+	}
+	f.amount += amount
+	return postDo()
+}
+
+// store expects the same usage in every window and compares it with the billing saved in the fake.
+type store struct {
+	*fake.Database
+	expected map[database.BillingUsageKey]int64
+}
+
+func (s *store) GetBillingUsage(_ context.Context, _, endTime time.Time, _ *resources.PropertyTypeLS, _ []string, owner string) (map[database.BillingUsageKey]int64, map[database.BillingUsageKey]database.BillingUsage, error) {
+	billed := make(map[database.BillingUsageKey]database.BillingUsage)
+	for _, b := range s.Billings() {
+		if b.Owner != owner || !b.Time.Equal(endTime) {
+			continue
+		}
+		for _, appCost := range b.AppCosts {
+			for property, used := range appCost.Used {
+				key := database.BillingUsageKey{Namespace: b.Namespace, AppType: b.AppType, Property: property}
+				usage := billed[key]
+				usage.Used += used
+				usage.Amount += appCost.UsedAmount[property]
+				billed[key] = usage
+			}
+		}
+	}
+	return s.expected, billed, nil
+}
+
+func TestRun(t *testing.T) {
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prols := resources.DefaultPropertyTypeLS
+	cpu := prols.StringMap["cpu"].Enum
+	key := database.BillingUsageKey{Namespace: "ns-test", AppType: resources.AppType[resources.APP], Property: cpu}
+	db := &store{Database: fake.NewDatabase(), expected: map[database.BillingUsageKey]int64{key: 1000}}
+	billing := func(id string, end time.Time, used, amount int64) *resources.Billing {
+		return &resources.Billing{OrderID: id, Owner: "owner1", Namespace: key.Namespace, AppType: key.AppType, Amount: amount, Time: end,
+			AppCosts: []resources.AppCost{{Name: "app1", Used: resources.EnumUsedMap{cpu: used}, UsedAmount: resources.EnumUsedMap{cpu: amount}, Amount: amount}}}
+	}
+	// the first hour is billed, the second hour is billed half of its usage, the third hour is not billed
+	if err := db.SaveBillings(context.Background(), billing("order1", startTime.Add(time.Hour), 1000, 2238), billing("order2", startTime.Add(2*time.Hour), 500, 1119)); err != nil {
+		t.Fatalf("failed to save billings: %v", err)
+	}
+
+	opts := Options{
+		Owner:      "owner1",
+		Namespaces: []string{"ns-test"},
+		StartTime:  startTime,
+		EndTime:    startTime.Add(3 * time.Hour),
+	}
+	report, err := Run(context.Background(), db, prols, opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(report.Discrepancies) != 2 || len(report.Corrections) != 0 {
+		t.Fatalf("Run() dry run report = %+v, want 2 discrepancies without correction", report)
+	}
+
+	deductor := &fakeDeductor{}
+	opts.Correct, opts.Deductor = true, deductor
+	report, err = Run(context.Background(), db, prols, opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(report.Discrepancies) != 2 || len(report.Corrections) != 1 {
+		t.Fatalf("Run() report = %+v, want 2 discrepancies and 1 correction", report)
+	}
+	d := report.Corrections[0].Discrepancy
+	if !d.Time.Equal(startTime.Add(2*time.Hour)) || d.Expected != 1000 || d.Actual != 500 || d.Amount != 1119 || deductor.amount != 1119 {
+		t.Errorf("Run() corrected discrepancy = %+v, deducted %d", d, deductor.amount)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	for _, want := range []string{
+		"owner1,2024-01-01T02:00:00Z,ns-test,APP,cpu,1000,500,500,0.001119," + report.Corrections[0].OrderID,
+		// the rate of an unbilled window is unknown
+		"owner1,2024-01-01T03:00:00Z,ns-test,APP,cpu,1000,0,1000,0.000000,\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteCSV() = %s, want row %s", buf.String(), want)
+		}
+	}
+
+	report, err = Run(context.Background(), db, prols, opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(report.Discrepancies) != 1 || deductor.amount != 1119 {
+		t.Errorf("Run() after correction = %+v, deducted %d", report, deductor.amount)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/labring/sealos/controllers/pkg/crypto"
+)
+
+const (
+	DevboxStorage = "devbox.storage"
+	DevboxCommit  = "devbox.commit"
+	DevboxNetwork = "devbox.network"
+)
+
+var devboxPropertyNames = map[string]bool{DevboxStorage: true, DevboxCommit: true, DevboxNetwork: true}
+
+// DevboxUsage is a usage record of one devbox, meant to be reported periodically by the devbox controller.
+// Devbox usage is kept apart from the monitors as commits are counted and storage is sampled rather than requested.
+// The records are storage only and not billed until the devbox controller reports them.
+type DevboxUsage struct {
+	Time      time.Time `json:"time" bson:"time"`
+	Owner     string    `json:"owner" bson:"owner"`
+	Namespace string    `json:"namespace" bson:"namespace"`
+	Name      string    `json:"name" bson:"name"`
+	// CommitCount is the number of commits since the previous record
+	CommitCount int64 `json:"commit_count" bson:"commit_count"`
+	// StorageBytes is the storage size of the devbox at Time
+	StorageBytes int64 `json:"storage_bytes" bson:"storage_bytes"`
+}
+
+// DevboxUsageSummary is the usage of one devbox in a time window.
+type DevboxUsageSummary struct {
+	Namespace   string `json:"namespace" bson:"namespace"`
+	Name        string `json:"name" bson:"name"`
+	CommitCount int64  `json:"commit_count" bson:"commit_count"`
+	// AvgStorageBytes is the average of the storage samples in the window
+	AvgStorageBytes float64 `json:"avg_storage_bytes" bson:"avg_storage_bytes"`
+}
+
+// NodePortTraffic is a traffic sample of the NodePorts of one devbox on one node, reported by the node agent.
+// The byte counters are the deltas since the previous sample.
+type NodePortTraffic struct {
+	Time         time.Time `json:"time" bson:"time"`
+	Namespace    string    `json:"namespace" bson:"namespace"`
+	Name         string    `json:"name" bson:"name"`
+	Node         string    `json:"node" bson:"node"`
+	NodePort     int32     `json:"nodePort" bson:"node_port"`
+	IngressBytes int64     `json:"ingressBytes" bson:"ingress_bytes"`
+	EgressBytes  int64     `json:"egressBytes" bson:"egress_bytes"`
+}
+
+// NodePortTrafficSummary is the NodePort traffic of one devbox in a time window.
+type NodePortTrafficSummary struct {
+	Namespace    string `json:"namespace" bson:"namespace"`
+	Name         string `json:"name" bson:"name"`
+	IngressBytes int64  `json:"ingressBytes" bson:"ingress_bytes"`
+	EgressBytes  int64  `json:"egressBytes" bson:"egress_bytes"`
+}
+
+// AppCost converts the summary of a window of the given hours into the used values of the devbox properties,
+// storage is used in GB-hours (Gi-hours), partial units are rounded up.
+// The amounts are set by PriceAppCosts.
+func (s DevboxUsageSummary) AppCost(prols *PropertyTypeLS, hours float64) AppCost {
+	appCost := AppCost{
+		Used: make(EnumUsedMap),
+		Name: s.Name,
+	}
+	if prop, ok := prols.StringMap[DevboxStorage]; ok && prop.Unit.Value() > 0 {
+		appCost.Used[prop.Enum] = int64(math.Ceil(s.AvgStorageBytes / float64(prop.Unit.Value()) * hours))
+	}
+	if prop, ok := prols.StringMap[DevboxCommit]; ok {
+		appCost.Used[prop.Enum] = s.CommitCount
+	}
+	return appCost
+}
+
+// MissingDevboxPropertyTypes returns the devbox property types that are not in types, with enums allocated after the
+// largest enum of types, so that a property list loaded from the database gets them without enum collisions.
+// The returned types are free until their price is set.
+func MissingDevboxPropertyTypes(types []PropertyType) ([]PropertyType, error) {
+	names := make(map[string]bool, len(types))
+	next := 0
+	for i := range types {
+		names[types[i].Name] = true
+		if int(types[i].Enum) >= next {
+			next = int(types[i].Enum) + 1
+		}
+	}
+	var missing []PropertyType
+	for _, prop := range DefaultPropertyTypeList {
+		if !devboxPropertyNames[prop.Name] || names[prop.Name] {
+			continue
+		}
+		if next > math.MaxUint8 {
+			return nil, fmt.Errorf("no enum left for property %s", prop.Name)
+		}
+		price, err := crypto.EncryptFloat64(prop.UnitPrice)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s unit price: %w", prop.Name, err)
+		}
+		prop.Enum, prop.EncryptUnitPrice = uint8(next), *price
+		next++
+		missing = append(missing, prop)
+	}
+	return missing, nil
+}
@@ -0,0 +1,55 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"fmt"
+	"time"
+)
+
+// Invoice is the monthly consumption statement of an owner, regenerating the invoice of the same
+// owner and period keeps its sequence number.
+type Invoice struct {
+	Owner       string        `json:"owner" bson:"owner"`
+	Sequence    int64         `json:"sequence" bson:"sequence"`
+	Number      string        `json:"number" bson:"number"`
+	PeriodStart time.Time     `json:"periodStart" bson:"period_start"`
+	PeriodEnd   time.Time     `json:"periodEnd" bson:"period_end"`
+	Items       []InvoiceItem `json:"items" bson:"items"`
+	Amount      int64         `json:"amount" bson:"amount"`
+	CreatedAt   time.Time     `json:"createdAt" bson:"created_at"`
+	UpdatedAt   time.Time     `json:"updatedAt" bson:"updated_at"`
+}
+
+// InvoiceItem is the consumption of one app type in one namespace.
+type InvoiceItem struct {
+	Namespace string `json:"namespace" bson:"namespace"`
+	AppType   string `json:"appType" bson:"app_type"`
+	// Count is the number of billing records summed into the item
+	Count  int64 `json:"count" bson:"count"`
+	Amount int64 `json:"amount" bson:"amount"`
+}
+
+// InvoicePeriod returns the month [start, end) that t belongs to in UTC.
+func InvoicePeriod(t time.Time) (start, end time.Time) {
+	t = t.UTC()
+	start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// InvoiceNumber formats the invoice number, eg: INV-202401-000001
+func InvoiceNumber(periodStart time.Time, sequence int64) string {
+	return fmt.Sprintf("INV-%s-%06d", periodStart.Format("200601"), sequence)
+}
@@ -16,6 +16,7 @@ package resources
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -162,6 +163,7 @@ const (
 	other
 	objectStorage
 	cvm
+	devbox
 )
 
 const (
@@ -172,14 +174,15 @@ const (
 	OTHER         = "OTHER"
 	ObjectStorage = "OBJECT-STORAGE"
 	CVM           = "CLOUD-VM"
+	DEVBOX        = "DEVBOX"
 )
 
 var AppType = map[string]uint8{
-	DB: db, APP: app, TERMINAL: terminal, JOB: job, OTHER: other, ObjectStorage: objectStorage, CVM: cvm,
+	DB: db, APP: app, TERMINAL: terminal, JOB: job, OTHER: other, ObjectStorage: objectStorage, CVM: cvm, DEVBOX: devbox,
 }
 
 var AppTypeReverse = map[uint8]string{
-	db: DB, app: APP, terminal: TERMINAL, job: JOB, other: OTHER, objectStorage: ObjectStorage, cvm: CVM,
+	db: DB, app: APP, terminal: TERMINAL, job: JOB, other: OTHER, objectStorage: ObjectStorage, cvm: CVM, devbox: DEVBOX,
 }
 
 // resource consumption
@@ -216,6 +219,117 @@ type PropertyType struct {
 	UnitString string `json:"unit" bson:"unit"`
 	//charging cycle second
 	UnitPeriod string `json:"unit_period,omitempty" bson:"unit_period,omitempty"`
+	// Tiers overrides UnitPrice with graduated prices by the total used value of the owner in a billing window
+	Tiers []PriceTier `json:"tiers,omitempty" bson:"tiers,omitempty"`
+	// Discounts are per owner rates applied to the amount
+	Discounts []Discount `json:"discounts,omitempty" bson:"discounts,omitempty"`
+}
+
+// PriceTier prices the used value in (previous tier UpTo, UpTo], the last tier without UpTo is unbounded.
+type PriceTier struct {
+	UpTo             int64   `json:"up_to,omitempty" bson:"up_to,omitempty"`
+	UnitPrice        float64 `json:"unit_price" bson:"unit_price"`
+	EncryptUnitPrice string  `json:"encrypt_unit_price,omitempty" bson:"encrypt_unit_price,omitempty"`
+}
+
+// Discount multiplies the amount of owner by Rate, eg: 0.8 = 20% off.
+// Rate is decrypted from EncryptRate when the property list is loaded, like the unit prices.
+type Discount struct {
+	Owner       string  `json:"owner" bson:"owner"`
+	Rate        float64 `json:"rate" bson:"rate"`
+	EncryptRate string  `json:"encrypt_rate,omitempty" bson:"encrypt_rate,omitempty"`
+}
+
+// HasPrice returns whether the used value of the property is charged.
+func (p PropertyType) HasPrice() bool {
+	if len(p.Tiers) == 0 {
+		return p.UnitPrice > 0
+	}
+	for i := range p.Tiers {
+		if p.Tiers[i].UnitPrice > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Amount calculates the amount of the used value in one billing window for owner.
+// Without tiers the used value is charged by UnitPrice, otherwise each tier charges the part of the used value
+// within its range, and the part beyond the last bounded tier is charged by the last tier.
+func (p PropertyType) Amount(used int64, owner string) int64 {
+	return int64(math.Ceil(p.amount(used, owner)))
+}
+
+// ShareAmount calculates the amount of the used value of one app, which is a part of the total used value of owner
+// in the same billing window. The tiers are chosen by the total, and each app pays its share of the amount of the total.
+func (p PropertyType) ShareAmount(used, total int64, owner string) int64 {
+	if len(p.Tiers) == 0 || used >= total {
+		return p.Amount(used, owner)
+	}
+	if used <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(p.amount(total, owner) * float64(used) / float64(total)))
+}
+
+func (p PropertyType) amount(used int64, owner string) float64 {
+	if used <= 0 {
+		return 0
+	}
+	var amount float64
+	if len(p.Tiers) == 0 {
+		amount = float64(used) * p.UnitPrice
+	} else {
+		prev := int64(0)
+		for i, tier := range p.Tiers {
+			upTo := tier.UpTo
+			if upTo == 0 || upTo > used || i == len(p.Tiers)-1 {
+				upTo = used
+			}
+			if upTo > prev {
+				amount += float64(upTo-prev) * tier.UnitPrice
+				prev = upTo
+			}
+			if prev >= used {
+				break
+			}
+		}
+	}
+	return amount * p.DiscountRate(owner)
+}
+
+// DiscountRate returns the discount rate of owner, 1 if owner has no discount.
+func (p PropertyType) DiscountRate(owner string) float64 {
+	for i := range p.Discounts {
+		if p.Discounts[i].Owner == owner {
+			return p.Discounts[i].Rate
+		}
+	}
+	return 1
+}
+
+// PriceAppCosts sets the amounts of the app costs of owner in one billing window from their used values.
+// The used values of all app costs are summed per property first, so that tiers apply to the usage of the owner
+// rather than to each app.
+func PriceAppCosts(prols *PropertyTypeLS, owner string, appCosts []*AppCost) {
+	total := make(EnumUsedMap)
+	for _, appCost := range appCosts {
+		for property, used := range appCost.Used {
+			if used > 0 {
+				total[property] += used
+			}
+		}
+	}
+	for _, appCost := range appCosts {
+		appCost.UsedAmount = make(EnumUsedMap)
+		appCost.Amount = 0
+		for property, used := range appCost.Used {
+			if prop, ok := prols.EnumMap[property]; ok && prop.HasPrice() {
+				appCost.UsedAmount[property] = prop.ShareAmount(used, total[property], owner)
+				appCost.Amount += appCost.UsedAmount[property]
+			}
+		}
+	}
 }
 
 type PropertyTypeLS struct {
@@ -274,6 +388,33 @@ var DefaultPropertyTypeList = []PropertyType{
 		ViewPrice:  2083,
 		UnitString: "1",
 	},
+	{
+		// devbox storage in GB-hours, from the devbox usage records.
+		// The devbox enums only hold for this list, a list loaded from the database gets its own, see MissingDevboxPropertyTypes.
+		Name:       DevboxStorage,
+		Enum:       5,
+		PriceType:  AVG,
+		UnitPrice:  0,
+		UnitString: "1Gi",
+	},
+	{
+		// devbox commits, from the devbox usage records
+		Name:       DevboxCommit,
+		Enum:       6,
+		PriceType:  SUM,
+		UnitPrice:  0,
+		UnitString: "1",
+	},
+	{
+		// devbox NodePort egress traffic, from the node agent records. It is priced apart from network
+		// as the network monitor may count the same pod traffic, only price one of them for devboxes.
+		// Not billed until the node agent records are ingested.
+		Name:       DevboxNetwork,
+		Enum:       7,
+		PriceType:  SUM,
+		UnitPrice:  0,
+		UnitString: "1Mi",
+	},
 }
 
 var DefaultPropertyTypeLS = newPropertyTypeLS(DefaultPropertyTypeList)
@@ -288,6 +429,9 @@ func ConvertEnumUsedToString(costs map[uint8]int64) (costsMap map[string]int64)
 
 func NewPropertyTypeLS(types []PropertyType) (ls *PropertyTypeLS) {
 	types, err := decryptPrice(types)
+	if err == nil {
+		err = validatePrice(types)
+	}
 	if err != nil {
 		logger.Warn("failed to decrypt price : %v", err)
 		types = DefaultPropertyTypeList
@@ -321,11 +465,52 @@ func decryptPrice(types []PropertyType) ([]PropertyType, error) {
 			return types, fmt.Errorf("failed to decrypt %s unit price : %v", types[i].Name, err)
 		}
 		types[i].UnitPrice = price
+		for j := range types[i].Tiers {
+			if types[i].Tiers[j].EncryptUnitPrice == "" {
+				return types, fmt.Errorf("encrypt %s tier %d unit price is empty", types[i].Name, j)
+			}
+			if types[i].Tiers[j].UnitPrice, err = crypto.DecryptFloat64(types[i].Tiers[j].EncryptUnitPrice); err != nil {
+				return types, fmt.Errorf("failed to decrypt %s tier %d unit price : %v", types[i].Name, j, err)
+			}
+		}
+		for j := range types[i].Discounts {
+			if types[i].Discounts[j].EncryptRate == "" {
+				return types, fmt.Errorf("encrypt %s discount rate of %s is empty", types[i].Name, types[i].Discounts[j].Owner)
+			}
+			if types[i].Discounts[j].Rate, err = crypto.DecryptFloat64(types[i].Discounts[j].EncryptRate); err != nil {
+				return types, fmt.Errorf("failed to decrypt %s discount rate of %s : %v", types[i].Name, types[i].Discounts[j].Owner, err)
+			}
+		}
 		logger.Info("parse properties", types[i].Enum, types[i].UnitPrice)
 	}
 	return types, nil
 }
 
+// validatePrice rejects tiers that are not sorted by their bounds, unbounded tiers other than the last one,
+// and discount rates out of [0, 1].
+func validatePrice(types []PropertyType) error {
+	for i := range types {
+		tiers := types[i].Tiers
+		for j := range tiers {
+			if tiers[j].UnitPrice < 0 {
+				return fmt.Errorf("%s tier %d unit price is negative", types[i].Name, j)
+			}
+			if tiers[j].UpTo < 0 || (tiers[j].UpTo == 0 && j < len(tiers)-1) {
+				return fmt.Errorf("%s tier %d is unbounded but not the last tier", types[i].Name, j)
+			}
+			if j > 0 && tiers[j].UpTo != 0 && tiers[j].UpTo <= tiers[j-1].UpTo {
+				return fmt.Errorf("%s tier %d overlaps the previous tier", types[i].Name, j)
+			}
+		}
+		for _, discount := range types[i].Discounts {
+			if discount.Rate < 0 || discount.Rate > 1 {
+				return fmt.Errorf("%s discount rate of %s is out of [0, 1]: %v", types[i].Name, discount.Owner, discount.Rate)
+			}
+		}
+	}
+	return nil
+}
+
 type PropertyTypeEnumMap map[uint8]PropertyType
 
 type PropertyTypeStringMap map[string]PropertyType
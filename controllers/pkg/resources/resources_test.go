@@ -0,0 +1,143 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import "testing"
+
+func TestPropertyType_Amount(t *testing.T) {
+	tiered := PropertyType{
+		Name: "cpu",
+		Tiers: []PriceTier{
+			{UpTo: 100, UnitPrice: 1},
+			{UpTo: 200, UnitPrice: 0.5},
+			{UnitPrice: 0.25},
+		},
+		Discounts: []Discount{{Owner: "vip", Rate: 0.8}},
+	}
+	bounded := PropertyType{
+		Name: "memory",
+		Tiers: []PriceTier{
+			{UpTo: 100, UnitPrice: 1},
+			{UpTo: 200, UnitPrice: 0.5},
+		},
+	}
+	flat := PropertyType{Name: "cpu", UnitPrice: 2.237442922}
+
+	tests := []struct {
+		name  string
+		prop  PropertyType
+		used  int64
+		owner string
+		want  int64
+	}{
+		{name: "zero used", prop: tiered, used: 0, want: 0},
+		{name: "first tier boundary", prop: tiered, used: 100, want: 100},
+		{name: "just above first tier", prop: tiered, used: 101, want: 101},
+		{name: "second tier boundary", prop: tiered, used: 200, want: 150},
+		{name: "unbounded tier", prop: tiered, used: 201, want: 151},
+		{name: "unbounded tier large", prop: tiered, used: 1200, want: 400},
+		{name: "beyond last bounded tier", prop: bounded, used: 300, want: 200},
+		{name: "owner discount", prop: tiered, used: 200, owner: "vip", want: 120},
+		{name: "other owner no discount", prop: tiered, used: 200, owner: "other", want: 150},
+		{name: "flat unit price", prop: flat, used: 1000, want: 2238},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.prop.Amount(tt.used, tt.owner); got != tt.want {
+				t.Errorf("Amount(%d, %q) = %d, want %d", tt.used, tt.owner, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPropertyType_HasPrice(t *testing.T) {
+	if (PropertyType{UnitPrice: 1, Tiers: []PriceTier{{UnitPrice: 0}}}).HasPrice() {
+		t.Errorf("HasPrice() = true for free tiers")
+	}
+	if !(PropertyType{Tiers: []PriceTier{{UpTo: 10}, {UnitPrice: 1}}}).HasPrice() {
+		t.Errorf("HasPrice() = false for priced tier")
+	}
+	if (PropertyType{}).HasPrice() {
+		t.Errorf("HasPrice() = true without price")
+	}
+}
+
+func TestPriceAppCosts(t *testing.T) {
+	prols := newPropertyTypeLS([]PropertyType{{
+		Name: "cpu",
+		Enum: 0,
+		Tiers: []PriceTier{
+			{UpTo: 100, UnitPrice: 1},
+			{UnitPrice: 0.5},
+		},
+	}, {
+		Name:      "memory",
+		Enum:      1,
+		UnitPrice: 1,
+	}})
+	appCosts := []*AppCost{
+		{Name: "a", Used: EnumUsedMap{0: 100, 1: 10}},
+		{Name: "b", Used: EnumUsedMap{0: 100}},
+	}
+	PriceAppCosts(prols, "owner", appCosts)
+	// 200 cpu of the owner cost 100 + 50, each app pays half of it
+	for _, appCost := range appCosts {
+		if appCost.UsedAmount[0] != 75 {
+			t.Errorf("app %s cpu amount = %d, want 75", appCost.Name, appCost.UsedAmount[0])
+		}
+	}
+	if appCosts[0].Amount != 85 || appCosts[1].Amount != 75 {
+		t.Errorf("amounts = %d, %d, want 85, 75", appCosts[0].Amount, appCosts[1].Amount)
+	}
+}
+
+func TestValidatePrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		prop    PropertyType
+		wantErr bool
+	}{
+		{name: "sorted tiers", prop: PropertyType{Tiers: []PriceTier{{UpTo: 10, UnitPrice: 1}, {UpTo: 20}, {}}}},
+		{name: "unsorted tiers", prop: PropertyType{Tiers: []PriceTier{{UpTo: 20, UnitPrice: 1}, {UpTo: 10}}}, wantErr: true},
+		{name: "overlapping tiers", prop: PropertyType{Tiers: []PriceTier{{UpTo: 10, UnitPrice: 1}, {UpTo: 10}}}, wantErr: true},
+		{name: "unbounded tier not last", prop: PropertyType{Tiers: []PriceTier{{UnitPrice: 1}, {UpTo: 10}}}, wantErr: true},
+		{name: "negative tier price", prop: PropertyType{Tiers: []PriceTier{{UnitPrice: -1}}}, wantErr: true},
+		{name: "discount rate", prop: PropertyType{Discounts: []Discount{{Owner: "vip", Rate: 0.8}}}},
+		{name: "negative discount rate", prop: PropertyType{Discounts: []Discount{{Owner: "vip", Rate: -0.1}}}, wantErr: true},
+		{name: "discount rate above one", prop: PropertyType{Discounts: []Discount{{Owner: "vip", Rate: 1.5}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePrice([]PropertyType{tt.prop}); (err != nil) != tt.wantErr {
+				t.Errorf("validatePrice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMissingDevboxPropertyTypes(t *testing.T) {
+	types := []PropertyType{{Name: "cpu", Enum: 0}, {Name: "gpu-tesla-v100", Enum: 7}, {Name: DevboxCommit, Enum: 3}}
+	missing, err := MissingDevboxPropertyTypes(types)
+	if err != nil {
+		t.Fatalf("MissingDevboxPropertyTypes() error = %v", err)
+	}
+	if len(missing) != 2 || missing[0].Name != DevboxStorage || missing[0].Enum != 8 || missing[0].EncryptUnitPrice == "" ||
+		missing[1].Name != DevboxNetwork || missing[1].Enum != 9 {
+		t.Errorf("MissingDevboxPropertyTypes() = %+v, want %s with enum 8 and %s with enum 9", missing, DevboxStorage, DevboxNetwork)
+	}
+	if _, err := MissingDevboxPropertyTypes([]PropertyType{{Name: "last", Enum: 255}}); err == nil {
+		t.Errorf("MissingDevboxPropertyTypes() without free enum error = nil")
+	}
+}
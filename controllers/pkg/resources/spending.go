@@ -0,0 +1,50 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import "time"
+
+// DefaultSpendingCapGracePeriod is used when a spending cap has no grace period.
+const DefaultSpendingCapGracePeriod = 24 * time.Hour
+
+// SpendingCap limits the monthly consumption of an owner, amount unit: 1000000 = 1¥
+type SpendingCap struct {
+	Owner        string `json:"owner" bson:"owner"`
+	MonthlyLimit int64  `json:"monthlyLimit" bson:"monthly_limit"`
+	// GracePeriodSeconds is the time between exceeding the cap and the suspension of the devboxes of owner
+	GracePeriodSeconds int64     `json:"gracePeriodSeconds" bson:"grace_period_seconds"`
+	UpdatedAt          time.Time `json:"updatedAt" bson:"updated_at"`
+}
+
+// GracePeriod returns the grace period of the cap, DefaultSpendingCapGracePeriod if it has none.
+func (c *SpendingCap) GracePeriod() time.Duration {
+	if c.GracePeriodSeconds <= 0 {
+		return DefaultSpendingCapGracePeriod
+	}
+	return time.Duration(c.GracePeriodSeconds) * time.Second
+}
+
+// SuspendRecommendation is created once per owner and month when the consumption exceeds the spending cap,
+// the devboxes of owner are meant to be stopped after SuspendAt. It is removed when the cap is changed or
+// deleted, or when the consumption is back within the cap.
+type SuspendRecommendation struct {
+	Owner       string    `json:"owner" bson:"owner"`
+	PeriodStart time.Time `json:"periodStart" bson:"period_start"`
+	Limit       int64     `json:"limit" bson:"limit"`
+	// Spent is the consumption of the period at the last evaluation
+	Spent     int64     `json:"spent" bson:"spent"`
+	CreatedAt time.Time `json:"createdAt" bson:"created_at"`
+	SuspendAt time.Time `json:"suspendAt" bson:"suspend_at"`
+}
@@ -0,0 +1,120 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeclient
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/labring/sealos/controllers/pkg/utils/env"
+)
+
+const (
+	defaultQPS   = float64(20.0)
+	defaultBurst = 30
+
+	EnvQPS               = "KUBE_CLIENT_QPS"
+	EnvBurst             = "KUBE_CLIENT_BURST"
+	EnvTimeout           = "KUBE_CLIENT_TIMEOUT"
+	EnvUserAgent         = "KUBE_CLIENT_USER_AGENT"
+	EnvImpersonateUser   = "KUBE_CLIENT_IMPERSONATE_USER"
+	EnvImpersonateGroups = "KUBE_CLIENT_IMPERSONATE_GROUPS"
+
+	flagQPS               = "kube-client-qps"
+	flagBurst             = "kube-client-burst"
+	flagTimeout           = "kube-client-timeout"
+	flagUserAgent         = "kube-client-user-agent"
+	flagImpersonateUser   = "kube-client-impersonate-user"
+	flagImpersonateGroups = "kube-client-impersonate-groups"
+)
+
+// Options tunes the rest config shared by the clients of a tool or controller,
+// flag defaults are taken from the KUBE_CLIENT_* env so they can be set cluster-wide.
+type Options struct {
+	QPS     float64
+	Burst   int
+	Timeout time.Duration
+	// UserAgent identifies the tool in the API server audit log and metrics.
+	UserAgent string
+	// ImpersonateUser and ImpersonateGroups (comma separated) are only applied when ImpersonateUser is set.
+	ImpersonateUser   string
+	ImpersonateGroups string
+}
+
+// BindFlags binds the options to fs, component is used as the default user agent.
+func (o *Options) BindFlags(fs *flag.FlagSet, component string) {
+	fs.Float64Var(&o.QPS, flagQPS, env.GetFloat64EnvWithDefault(EnvQPS, defaultQPS), "The maximum queries per second to the kubernetes API server.")
+	fs.IntVar(&o.Burst, flagBurst, env.GetIntEnvWithDefault(EnvBurst, defaultBurst), "The maximum burst of queries to the kubernetes API server.")
+	fs.DurationVar(&o.Timeout, flagTimeout, env.GetDurationEnvWithDefault(EnvTimeout, 0), "The timeout of a single request to the kubernetes API server, zero means no timeout.")
+	fs.StringVar(&o.UserAgent, flagUserAgent, env.GetEnvWithDefault(EnvUserAgent, DefaultUserAgent(component)), "The user agent sent to the kubernetes API server.")
+	fs.StringVar(&o.ImpersonateUser, flagImpersonateUser, os.Getenv(EnvImpersonateUser), "The user to impersonate for requests to the kubernetes API server.")
+	fs.StringVar(&o.ImpersonateGroups, flagImpersonateGroups, os.Getenv(EnvImpersonateGroups), "The comma separated groups to impersonate, requires the impersonate user.")
+}
+
+// DefaultUserAgent is "sealos-<component>/<client-go user agent>".
+func DefaultUserAgent(component string) string {
+	return "sealos-" + component + "/" + rest.DefaultKubernetesUserAgent()
+}
+
+// Apply returns a copy of cfg with the options set, zero values keep the settings of cfg.
+func (o *Options) Apply(cfg *rest.Config) *rest.Config {
+	cfg = rest.CopyConfig(cfg)
+	if o.QPS > 0 {
+		cfg.QPS = float32(o.QPS)
+	}
+	if o.Burst > 0 {
+		cfg.Burst = o.Burst
+	}
+	if o.Timeout > 0 {
+		cfg.Timeout = o.Timeout
+	}
+	if o.UserAgent != "" {
+		cfg.UserAgent = o.UserAgent
+	}
+	if o.ImpersonateUser != "" {
+		cfg.Impersonate = rest.ImpersonationConfig{UserName: o.ImpersonateUser}
+		for _, group := range strings.Split(o.ImpersonateGroups, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				cfg.Impersonate.Groups = append(cfg.Impersonate.Groups, group)
+			}
+		}
+	}
+	return cfg
+}
+
+// GetConfig loads the config the same way as ctrl.GetConfig (--kubeconfig, KUBECONFIG, in-cluster) and applies the options.
+func (o *Options) GetConfig() (*rest.Config, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	}
+	return o.Apply(cfg), nil
+}
+
+// GetConfigOrDie is GetConfig that exits the program on error, like ctrl.GetConfigOrDie.
+func (o *Options) GetConfigOrDie() *rest.Config {
+	cfg, err := o.GetConfig()
+	if err != nil {
+		ctrl.Log.WithName("kubeclient").Error(err, "unable to get kubeconfig")
+		os.Exit(1)
+	}
+	return cfg
+}
@@ -0,0 +1,48 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeclient
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestOptions_Apply(t *testing.T) {
+	t.Setenv(EnvBurst, "50")
+	var o Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.BindFlags(fs, "account")
+	if err := fs.Parse([]string{"--kube-client-qps=7.5", "--kube-client-timeout=30s",
+		"--kube-client-impersonate-user=admin", "--kube-client-impersonate-groups=system:masters, ops"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	base := &rest.Config{Host: "https://127.0.0.1:6443", QPS: 20, Burst: 30}
+	cfg := o.Apply(base)
+	if cfg.QPS != 7.5 || cfg.Burst != 50 || cfg.Timeout != 30*time.Second || cfg.UserAgent != DefaultUserAgent("account") {
+		t.Errorf("Apply() = qps %v, burst %d, timeout %v, user agent %s", cfg.QPS, cfg.Burst, cfg.Timeout, cfg.UserAgent)
+	}
+	want := rest.ImpersonationConfig{UserName: "admin", Groups: []string{"system:masters", "ops"}}
+	if !reflect.DeepEqual(cfg.Impersonate, want) {
+		t.Errorf("Apply() impersonate = %+v, want %+v", cfg.Impersonate, want)
+	}
+	if base.QPS != 20 || base.UserAgent != "" {
+		t.Errorf("Apply() modified the base config")
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	retryAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sealos_retry_attempts_total",
+		Help: "Number of retried attempts of an operation",
+	}, []string{"operation"})
+	retryExhausted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sealos_retry_exhausted_total",
+		Help: "Number of operations that failed after all retries or with a non-retryable error",
+	}, []string{"operation"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(retryAttempts, retryExhausted)
+}
+
+// Backoff is an exponential backoff, the delay before attempt n+1 is Duration * Factor^(n-1)
+// capped at Cap, with up to Jitter * delay added.
+type Backoff struct {
+	// Steps is the maximum number of attempts
+	Steps    int
+	Duration time.Duration
+	Factor   float64
+	Jitter   float64
+	Cap      time.Duration
+}
+
+// DefaultBackoff suits api server and database calls of a reconcile.
+var DefaultBackoff = Backoff{
+	Steps:    5,
+	Duration: 100 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+	Cap:      10 * time.Second,
+}
+
+// Delay returns the delay after the failed attempt (starting from 1).
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := float64(b.Duration)
+	for i := 1; i < attempt && b.Factor > 1; i++ {
+		delay *= b.Factor
+		if b.Cap > 0 && delay >= float64(b.Cap) {
+			break
+		}
+	}
+	if b.Cap > 0 && delay > float64(b.Cap) {
+		delay = float64(b.Cap)
+	}
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * rand.Float64() //nolint:gosec
+	}
+	return time.Duration(delay)
+}
+
+type Options struct {
+	// Name labels the retry metrics, no metrics are recorded without it
+	Name    string
+	Backoff Backoff
+	// MaxElapsed bounds the total time of the attempts, no attempt is made after the next delay would exceed it,
+	// unbounded if zero. Set it below the reconcile budget when retrying inside a reconcile.
+	MaxElapsed time.Duration
+	// Retryable classifies the errors worth another attempt, all errors are retried if nil
+	Retryable func(error) bool
+	// OnRetry is called before waiting for the next attempt
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Do runs action until it succeeds, returns a non-retryable error, the attempts or MaxElapsed are used up or ctx is done.
+func Do(ctx context.Context, opts Options, action func(ctx context.Context) error) error {
+	steps := opts.Backoff.Steps
+	if steps <= 0 {
+		steps = 1
+	}
+	start := time.Now()
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = action(ctx); err == nil {
+			return nil
+		}
+		if attempt >= steps || (opts.Retryable != nil && !opts.Retryable(err)) {
+			break
+		}
+		delay := opts.Backoff.Delay(attempt)
+		if opts.MaxElapsed > 0 && time.Since(start)+delay > opts.MaxElapsed {
+			break
+		}
+		if opts.OnRetry != nil {
+			opts.OnRetry(attempt, err, delay)
+		}
+		if opts.Name != "" {
+			retryAttempts.WithLabelValues(opts.Name).Inc()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("retry canceled: %w", errors.Join(err, ctx.Err()))
+		case <-timer.C:
+		}
+	}
+	if opts.Name != "" {
+		retryExhausted.WithLabelValues(opts.Name).Inc()
+	}
+	return err
+}
+
+// IsRetryableKubeError reports whether err is a transient api server error: conflicts, already exists
+// (the create race of CreateOrUpdate), throttling, timeouts, unavailable or internal server errors,
+// and the network errors of IsRetryableNetworkError.
+func IsRetryableKubeError(err error) bool {
+	return apierrors.IsConflict(err) ||
+		apierrors.IsAlreadyExists(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsUnexpectedServerError(err) ||
+		IsRetryableNetworkError(err)
+}
+
+// IsRetryableNetworkError reports whether err is a transient transport error: refused or reset connections,
+// unexpected EOFs and network timeouts.
+func IsRetryableNetworkError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if utilnet.IsConnectionRefused(err) || utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
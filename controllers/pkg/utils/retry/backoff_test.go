@@ -0,0 +1,123 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	b := Backoff{Duration: time.Second, Factor: 2, Cap: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := b.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	b.Jitter = 0.5
+	if got := b.Delay(1); got < time.Second || got > 1500*time.Millisecond {
+		t.Errorf("Delay(1) with jitter = %v, want within [1s, 1.5s]", got)
+	}
+}
+
+func TestDo(t *testing.T) {
+	conflict := apierrors.NewConflict(schema.GroupResource{Resource: "limitranges"}, "test", errors.New("modified"))
+	opts := Options{
+		Backoff:   Backoff{Steps: 3, Duration: time.Millisecond},
+		Retryable: IsRetryableKubeError,
+	}
+
+	attempts := 0
+	err := Do(context.Background(), opts, func(context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return conflict
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("Do() = %v after %d attempts, want success after 3", err, attempts)
+	}
+
+	attempts = 0
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "limitranges"}, "test")
+	if err := Do(context.Background(), opts, func(context.Context) error {
+		attempts++
+		return notFound
+	}); !apierrors.IsNotFound(err) || attempts != 1 {
+		t.Errorf("Do() = %v after %d attempts, want not found after 1", err, attempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts.Backoff.Duration = time.Hour
+	if err := Do(ctx, opts, func(context.Context) error {
+		return conflict
+	}); !errors.Is(err, context.Canceled) || !apierrors.IsConflict(err) {
+		t.Errorf("Do() with canceled context = %v", err)
+	}
+}
+
+func TestDo_MaxElapsed(t *testing.T) {
+	opts := Options{
+		Backoff:    Backoff{Steps: 10, Duration: 20 * time.Millisecond},
+		MaxElapsed: 50 * time.Millisecond,
+	}
+	attempts := 0
+	start := time.Now()
+	if err := Do(context.Background(), opts, func(context.Context) error {
+		attempts++
+		return io.EOF
+	}); !errors.Is(err, io.EOF) || attempts < 2 || attempts >= opts.Backoff.Steps {
+		t.Errorf("Do() = %v after %d attempts, want EOF before the attempts are used up", err, attempts)
+	}
+	if elapsed := time.Since(start); elapsed > opts.MaxElapsed {
+		t.Errorf("Do() took %v, want at most %v", elapsed, opts.MaxElapsed)
+	}
+}
+
+func TestIsRetryableKubeError(t *testing.T) {
+	gr := schema.GroupResource{Resource: "limitranges"}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"conflict", apierrors.NewConflict(gr, "test", errors.New("modified")), true},
+		{"already exists", apierrors.NewAlreadyExists(gr, "test"), true},
+		{"too many requests", apierrors.NewTooManyRequests("slow down", 1), true},
+		{"connection refused", fmt.Errorf("get limitrange: %w", refused), true},
+		{"eof", fmt.Errorf("get limitrange: %w", io.EOF), true},
+		{"not found", apierrors.NewNotFound(gr, "test"), false},
+		{"invalid", apierrors.NewBadRequest("invalid"), false},
+		{"other", errors.New("mutate failed"), false},
+	} {
+		if got := IsRetryableKubeError(tc.err); got != tc.want {
+			t.Errorf("IsRetryableKubeError(%s) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
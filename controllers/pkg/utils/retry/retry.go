@@ -19,6 +19,9 @@ import (
 	"time"
 )
 
+// Retry runs action up to tryTimes, retrying every error.
+//
+// Deprecated: use Do, which honors the context and classifies the errors.
 func Retry(tryTimes int, trySleepTime time.Duration, action func() error) error {
 	var err error
 	for i := 0; i < tryTimes; i++ {
@@ -37,6 +37,9 @@ func mutate(f MutateFn, key client.ObjectKey, obj client.Object) error {
 
 type MutateFn func() error
 
+// CreateOrUpdate is controllerutil.CreateOrUpdate retried with Retry.
+//
+// Deprecated: retry controllerutil.CreateOrUpdate with Do and IsRetryableKubeError.
 func CreateOrUpdate(ctx context.Context, c client.Client, obj client.Object, f MutateFn, tryTimes int, trySleepTime time.Duration) (OperationResult, error) {
 	var result OperationResult
 	err := Retry(tryTimes, trySleepTime, func() error {
@@ -69,7 +69,7 @@ type MonitorReconciler struct {
 	periodicReconcile       time.Duration
 	NvidiaGpu               map[string]gpu.NvidiaGPU
 	DBClient                database.Interface
-	TrafficClient           database.Interface
+	TrafficClient           database.Traffic
 	Properties              *resources.PropertyTypeLS
 	PromURL                 string
 	currentObjectMetrics    map[string]objstorage.MetricData
@@ -118,7 +118,10 @@ func NewMonitorReconciler(mgr ctrl.Manager) (*MonitorReconciler, error) {
 	}
 	concurrentLimit = env.GetInt64EnvWithDefault(ConcurrentLimit, DefaultConcurrencyLimit)
 	var err error
-	err = retry.Retry(2, 1*time.Second, func() error {
+	err = retry.Do(context.Background(), retry.Options{
+		Name:    "get-gpu-model",
+		Backoff: retry.Backoff{Steps: 2, Duration: time.Second},
+	}, func(context.Context) error {
 		r.NvidiaGpu, err = gpu.GetNodeGpuModel(mgr.GetClient())
 		if err != nil {
 			return fmt.Errorf("failed to get node gpu model: %v", err)
@@ -478,7 +481,7 @@ func (r *MonitorReconciler) handlerObjectStorageTrafficUsed(startTime, endTime t
 }
 
 func (r *MonitorReconciler) monitorPodTrafficUsed(startTime, endTime time.Time) error {
-	monitors, err := r.DBClient.GetDistinctMonitorCombinations(startTime, endTime)
+	monitors, err := r.DBClient.GetDistinctMonitorCombinations(context.Background(), startTime, endTime)
 	if err != nil {
 		return fmt.Errorf("failed to get distinct monitor combinations: %w", err)
 	}
@@ -495,7 +498,7 @@ func (r *MonitorReconciler) monitorPodTrafficUsed(startTime, endTime time.Time)
 }
 
 func (r *MonitorReconciler) handlerTrafficUsed(startTime, endTime time.Time, monitor resources.Monitor) error {
-	bytes, err := r.TrafficClient.GetTrafficSentBytes(startTime, endTime, monitor.Category, monitor.Type, monitor.Name)
+	bytes, err := r.TrafficClient.GetTrafficSentBytes(context.Background(), startTime, endTime, monitor.Category, monitor.Type, monitor.Name)
 	if err != nil {
 		return fmt.Errorf("failed to get traffic sent bytes: %w", err)
 	}
@@ -555,5 +558,28 @@ func initGpuResources() *quantity {
 }
 
 func (r *MonitorReconciler) DropMonitorCollectionOlder() error {
-	return r.DBClient.DropMonitorCollectionsOlderThan(30)
+	return r.DBClient.DropMonitorCollectionsOlderThan(context.Background(), 30)
+}
+
+// trafficArchiveMargin is how long before its expiry the traffic is archived, more than the daily run interval
+// so that a failed run is caught up by the next one.
+const trafficArchiveMargin = 48 * time.Hour
+
+// ArchiveExpiringTraffic copies the traffic that the ttl removes within trafficArchiveMargin into the archive,
+// nothing is archived if the traffic never expires.
+func (r *MonitorReconciler) ArchiveExpiringTraffic() error {
+	ttl, err := r.TrafficClient.GetTrafficTTL(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get traffic ttl: %v", err)
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	before := time.Now().UTC().Add(trafficArchiveMargin - ttl)
+	count, err := r.TrafficClient.ArchiveTrafficBefore(context.Background(), before)
+	if err != nil {
+		return fmt.Errorf("failed to archive traffic before %s: %v", before.Format(time.RFC3339), err)
+	}
+	r.Logger.Info("archived traffic", "before", before.Format(time.RFC3339), "count", count)
+	return nil
 }
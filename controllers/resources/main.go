@@ -33,6 +33,7 @@ import (
 
 	"github.com/labring/sealos/controllers/pkg/resources"
 
+	"github.com/labring/sealos/controllers/pkg/utils/kubeclient"
 	"github.com/labring/sealos/controllers/resources/controllers"
 
 	objectstoragev1 "github/labring/sealos/controllers/objectstorage/api/v1"
@@ -64,6 +65,7 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var kubeClientOptions kubeclient.Options
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -72,12 +74,13 @@ func main() {
 	opts := zap.Options{
 		Development: true,
 	}
+	kubeClientOptions.BindFlags(flag.CommandLine, "resources-controller")
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgr, err := ctrl.NewManager(kubeClientOptions.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
@@ -129,13 +132,24 @@ func main() {
 		}
 	}()
 	if trafficURI := os.Getenv(database.TrafficMongoURI); trafficURI != "" {
-		reconciler.TrafficClient, err = mongo.NewMongoInterface(context.Background(), trafficURI)
+		trafficClient, err := mongo.NewMongoInterface(context.Background(), trafficURI)
 		if err != nil {
 			setupLog.Error(err, "failed to init traffic db client")
 			os.Exit(1)
 		}
+		reconciler.TrafficClient = trafficClient
+		// the traffic collections live on their own client, the account controller only builds the indexes of its client.
+		// The nodeport traffic is created as a time series before any record is inserted, an insert would create a plain collection.
+		if err := trafficClient.CreateNodePortTrafficIfNotExist(context.Background()); err != nil {
+			setupLog.Error(err, "failed to create nodeport traffic collection")
+		}
+		go func() {
+			if err := trafficClient.EnsureIndexes(context.Background()); err != nil {
+				setupLog.Error(err, "failed to ensure traffic db indexes")
+			}
+		}()
 		defer func() {
-			if err := reconciler.TrafficClient.Disconnect(context.Background()); err != nil {
+			if err := trafficClient.Disconnect(context.Background()); err != nil {
 				setupLog.Error(err, "failed to disconnect traffic db client")
 			}
 		}()
@@ -143,7 +157,7 @@ func main() {
 		setupLog.Info("traffic mongo uri not found, please check env: TRAFFIC_MONGO_URI")
 	}
 
-	err = reconciler.DBClient.InitDefaultPropertyTypeLS()
+	err = reconciler.DBClient.InitDefaultPropertyTypeLS(context.Background())
 	if err != nil {
 		setupLog.Error(err, "failed to get property type")
 		os.Exit(1)
@@ -187,13 +201,18 @@ func main() {
 		ticker := time.NewTicker(24 * time.Hour)
 		defer ticker.Stop()
 		for {
-			err := reconciler.DBClient.CreateMonitorTimeSeriesIfNotExist(time.Now().UTC().Add(24 * time.Hour))
+			err := reconciler.DBClient.CreateMonitorTimeSeriesIfNotExist(context.Background(), time.Now().UTC().Add(24*time.Hour))
 			if err != nil {
 				reconciler.Logger.Error(err, "failed to create monitor time series")
 			}
 			if err := reconciler.DropMonitorCollectionOlder(); err != nil {
 				reconciler.Logger.Error(err, "failed to drop monitor collection")
 			}
+			if reconciler.TrafficClient != nil {
+				if err := reconciler.ArchiveExpiringTraffic(); err != nil {
+					reconciler.Logger.Error(err, "failed to archive traffic")
+				}
+			}
 			<-ticker.C
 		}
 	})
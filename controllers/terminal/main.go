@@ -32,6 +32,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	configpkg "github.com/labring/sealos/controllers/pkg/config"
+	"github.com/labring/sealos/controllers/pkg/utils/kubeclient"
 	terminalv1 "github.com/labring/sealos/controllers/terminal/api/v1"
 	"github.com/labring/sealos/controllers/terminal/controllers"
 	//+kubebuilder:scaffold:imports
@@ -52,6 +53,7 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var kubeClientOptions kubeclient.Options
 	var configFilePath string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -62,12 +64,13 @@ func main() {
 	opts := zap.Options{
 		Development: true,
 	}
+	kubeClientOptions.BindFlags(flag.CommandLine, "terminal-controller")
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgr, err := ctrl.NewManager(kubeClientOptions.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,